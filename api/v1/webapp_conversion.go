@@ -0,0 +1,320 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	webappv2 "github.com/hoon77/crd-operator/api/v2"
+)
+
+// v1 and v2 carry the same information, v2 only groups it into the workload,
+// networking, config and lifecycle sections. Both directions are lossless.
+
+// ConvertTo converts this WebApp to the hub version (v2).
+func (src *WebApp) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*webappv2.WebApp)
+	dst.ObjectMeta = src.ObjectMeta
+
+	spec := &src.Spec
+	dst.Spec = webappv2.WebAppSpec{
+		TemplateRef: (*webappv2.WebAppTemplateReference)(spec.TemplateRef),
+		Workload: webappv2.WorkloadSpec{
+			Image:               spec.Image,
+			ImagePolicy:         (*webappv2.ImagePolicySpec)(spec.ImagePolicy),
+			Replicas:            spec.Replicas,
+			InitContainers:      spec.InitContainers,
+			Sidecars:            spec.Sidecars,
+			Volumes:             spec.Volumes,
+			VolumeMounts:        spec.VolumeMounts,
+			Storage:             convertSlice(spec.Storage, func(in StorageSpec) webappv2.StorageSpec { return webappv2.StorageSpec(in) }),
+			Scheduling:          (*webappv2.SchedulingSpec)(spec.Scheduling),
+			PodDisruptionBudget: (*webappv2.PodDisruptionBudgetSpec)(spec.PodDisruptionBudget),
+			Resources:           spec.Resources,
+			LivenessProbe:       spec.LivenessProbe,
+			ReadinessProbe:      spec.ReadinessProbe,
+			SecurityContext:     spec.SecurityContext,
+			PodSecurityContext:  spec.PodSecurityContext,
+			ServiceAccount:      (*webappv2.ServiceAccountSpec)(spec.ServiceAccount),
+			Hibernate:           spec.Hibernate,
+			Schedules:           convertSlice(spec.Schedules, func(in ScheduleSpec) webappv2.ScheduleSpec { return webappv2.ScheduleSpec(in) }),
+		},
+		Networking: webappv2.NetworkingSpec{
+			Ingress:       convertIngressTo(spec.Ingress),
+			Routing:       convertRoutingTo(spec.Routing),
+			NetworkPolicy: convertNetworkPolicyTo(spec.NetworkPolicy),
+		},
+		Config: webappv2.ConfigSpec{
+			Data: spec.ConfigData,
+		},
+		Lifecycle: webappv2.LifecycleSpec{
+			Suspend:        spec.Suspend,
+			DeletionPolicy: convertDeletionPolicyTo(spec.DeletionPolicy),
+			AdoptionPolicy: webappv2.AdoptionPolicy(spec.AdoptionPolicy),
+		},
+	}
+
+	dst.Status = webappv2.WebAppStatus{
+		Replicas:          src.Status.Replicas,
+		AvailableReplicas: src.Status.AvailableReplicas,
+		Phase:             webappv2.WebAppPhase(src.Status.Phase),
+		CleanupBlockers:   src.Status.CleanupBlockers,
+		ActiveSchedule:    src.Status.ActiveSchedule,
+		URL:               src.Status.URL,
+		ServiceEndpoints:  convertSlice(src.Status.ServiceEndpoints, func(in ServiceEndpoint) webappv2.ServiceEndpoint { return webappv2.ServiceEndpoint(in) }),
+		IngressAddresses:  src.Status.IngressAddresses,
+		ResolvedImage:     src.Status.ResolvedImage,
+		ImagePolicy:       (*webappv2.ImagePolicyStatus)(src.Status.ImagePolicy),
+		Conditions:        src.Status.Conditions,
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub version (v2) to this WebApp.
+func (dst *WebApp) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*webappv2.WebApp)
+	dst.ObjectMeta = src.ObjectMeta
+
+	workload := &src.Spec.Workload
+	networking := &src.Spec.Networking
+	lifecycle := &src.Spec.Lifecycle
+	dst.Spec = WebAppSpec{
+		Image:               workload.Image,
+		ImagePolicy:         (*ImagePolicySpec)(workload.ImagePolicy),
+		TemplateRef:         (*WebAppTemplateReference)(src.Spec.TemplateRef),
+		Replicas:            workload.Replicas,
+		ConfigData:          src.Spec.Config.Data,
+		Ingress:             convertIngressFrom(networking.Ingress),
+		InitContainers:      workload.InitContainers,
+		Sidecars:            workload.Sidecars,
+		Volumes:             workload.Volumes,
+		VolumeMounts:        workload.VolumeMounts,
+		Storage:             convertSlice(workload.Storage, func(in webappv2.StorageSpec) StorageSpec { return StorageSpec(in) }),
+		Scheduling:          (*SchedulingSpec)(workload.Scheduling),
+		PodDisruptionBudget: (*PodDisruptionBudgetSpec)(workload.PodDisruptionBudget),
+		Resources:           workload.Resources,
+		LivenessProbe:       workload.LivenessProbe,
+		ReadinessProbe:      workload.ReadinessProbe,
+		SecurityContext:     workload.SecurityContext,
+		PodSecurityContext:  workload.PodSecurityContext,
+		ServiceAccount:      (*ServiceAccountSpec)(workload.ServiceAccount),
+		NetworkPolicy:       convertNetworkPolicyFrom(networking.NetworkPolicy),
+		Routing:             convertRoutingFrom(networking.Routing),
+		DeletionPolicy:      convertDeletionPolicyFrom(lifecycle.DeletionPolicy),
+		AdoptionPolicy:      AdoptionPolicy(lifecycle.AdoptionPolicy),
+		Suspend:             lifecycle.Suspend,
+		Hibernate:           workload.Hibernate,
+		Schedules:           convertSlice(workload.Schedules, func(in webappv2.ScheduleSpec) ScheduleSpec { return ScheduleSpec(in) }),
+	}
+
+	dst.Status = WebAppStatus{
+		Replicas:          src.Status.Replicas,
+		AvailableReplicas: src.Status.AvailableReplicas,
+		Phase:             WebAppPhase(src.Status.Phase),
+		CleanupBlockers:   src.Status.CleanupBlockers,
+		ActiveSchedule:    src.Status.ActiveSchedule,
+		URL:               src.Status.URL,
+		ServiceEndpoints:  convertSlice(src.Status.ServiceEndpoints, func(in webappv2.ServiceEndpoint) ServiceEndpoint { return ServiceEndpoint(in) }),
+		IngressAddresses:  src.Status.IngressAddresses,
+		ResolvedImage:     src.Status.ResolvedImage,
+		ImagePolicy:       (*ImagePolicyStatus)(src.Status.ImagePolicy),
+		Conditions:        src.Status.Conditions,
+	}
+	return nil
+}
+
+func convertIngressTo(in *IngressSpec) *webappv2.IngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := &webappv2.IngressSpec{
+		Enabled:       in.Enabled,
+		ClassName:     in.ClassName,
+		Host:          in.Host,
+		Path:          in.Path,
+		Port:          in.Port,
+		RewriteTarget: in.RewriteTarget,
+		TLS:           in.TLS,
+		HTTP: webappv2.IngressHTTPOptions{
+			SSLRedirect:         in.SSLRedirect,
+			MaxBodySize:         in.MaxBodySize,
+			Timeouts:            (*webappv2.IngressTimeouts)(in.Timeouts),
+			CORS:                (*webappv2.IngressCORS)(in.CORS),
+			RateLimit:           (*webappv2.IngressRateLimit)(in.RateLimit),
+			AllowedSourceRanges: in.AllowedSourceRanges,
+		},
+		Annotations: in.Annotations,
+	}
+	if in.Auth != nil {
+		out.HTTP.Auth = &webappv2.IngressAuth{
+			Basic:    (*webappv2.IngressBasicAuth)(in.Auth.Basic),
+			External: (*webappv2.IngressExternalAuth)(in.Auth.External),
+		}
+	}
+	return out
+}
+
+func convertIngressFrom(in *webappv2.IngressSpec) *IngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := &IngressSpec{
+		Enabled:             in.Enabled,
+		ClassName:           in.ClassName,
+		Host:                in.Host,
+		Path:                in.Path,
+		Port:                in.Port,
+		RewriteTarget:       in.RewriteTarget,
+		TLS:                 in.TLS,
+		SSLRedirect:         in.HTTP.SSLRedirect,
+		MaxBodySize:         in.HTTP.MaxBodySize,
+		Timeouts:            (*IngressTimeouts)(in.HTTP.Timeouts),
+		CORS:                (*IngressCORS)(in.HTTP.CORS),
+		RateLimit:           (*IngressRateLimit)(in.HTTP.RateLimit),
+		AllowedSourceRanges: in.HTTP.AllowedSourceRanges,
+		Annotations:         in.Annotations,
+	}
+	if in.HTTP.Auth != nil {
+		out.Auth = &IngressAuth{
+			Basic:    (*IngressBasicAuth)(in.HTTP.Auth.Basic),
+			External: (*IngressExternalAuth)(in.HTTP.Auth.External),
+		}
+	}
+	return out
+}
+
+func convertRoutingTo(in *RoutingSpec) *webappv2.RoutingSpec {
+	if in == nil {
+		return nil
+	}
+	out := &webappv2.RoutingSpec{Type: webappv2.RoutingType(in.Type)}
+	if in.Gateway != nil {
+		out.Gateway = &webappv2.GatewayRouteSpec{
+			ParentRefs: convertSlice(in.Gateway.ParentRefs, func(in GatewayParentReference) webappv2.GatewayParentReference {
+				return webappv2.GatewayParentReference(in)
+			}),
+			Hostnames: in.Gateway.Hostnames,
+			Rules: convertSlice(in.Gateway.Rules, func(in GatewayRouteRule) webappv2.GatewayRouteRule {
+				return webappv2.GatewayRouteRule(in)
+			}),
+		}
+	}
+	return out
+}
+
+func convertRoutingFrom(in *webappv2.RoutingSpec) *RoutingSpec {
+	if in == nil {
+		return nil
+	}
+	out := &RoutingSpec{Type: RoutingType(in.Type)}
+	if in.Gateway != nil {
+		out.Gateway = &GatewayRouteSpec{
+			ParentRefs: convertSlice(in.Gateway.ParentRefs, func(in webappv2.GatewayParentReference) GatewayParentReference {
+				return GatewayParentReference(in)
+			}),
+			Hostnames: in.Gateway.Hostnames,
+			Rules: convertSlice(in.Gateway.Rules, func(in webappv2.GatewayRouteRule) GatewayRouteRule {
+				return GatewayRouteRule(in)
+			}),
+		}
+	}
+	return out
+}
+
+func convertNetworkPolicyTo(in *NetworkPolicySpec) *webappv2.NetworkPolicySpec {
+	if in == nil {
+		return nil
+	}
+	return &webappv2.NetworkPolicySpec{
+		Enabled:                    in.Enabled,
+		DefaultDeny:                in.DefaultDeny,
+		IngressControllerNamespace: in.IngressControllerNamespace,
+		AllowFromNamespaces:        in.AllowFromNamespaces,
+		AllowFrom:                  in.AllowFrom,
+		Egress: convertSlice(in.Egress, func(in NetworkPolicyEgressRule) webappv2.NetworkPolicyEgressRule {
+			return webappv2.NetworkPolicyEgressRule{
+				CIDRs: in.CIDRs,
+				WebApps: convertSlice(in.WebApps, func(in WebAppReference) webappv2.WebAppReference {
+					return webappv2.WebAppReference(in)
+				}),
+				Ports: in.Ports,
+			}
+		}),
+	}
+}
+
+func convertNetworkPolicyFrom(in *webappv2.NetworkPolicySpec) *NetworkPolicySpec {
+	if in == nil {
+		return nil
+	}
+	return &NetworkPolicySpec{
+		Enabled:                    in.Enabled,
+		DefaultDeny:                in.DefaultDeny,
+		IngressControllerNamespace: in.IngressControllerNamespace,
+		AllowFromNamespaces:        in.AllowFromNamespaces,
+		AllowFrom:                  in.AllowFrom,
+		Egress: convertSlice(in.Egress, func(in webappv2.NetworkPolicyEgressRule) NetworkPolicyEgressRule {
+			return NetworkPolicyEgressRule{
+				CIDRs: in.CIDRs,
+				WebApps: convertSlice(in.WebApps, func(in webappv2.WebAppReference) WebAppReference {
+					return WebAppReference(in)
+				}),
+				Ports: in.Ports,
+			}
+		}),
+	}
+}
+
+func convertDeletionPolicyTo(in *DeletionPolicySpec) *webappv2.DeletionPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := &webappv2.DeletionPolicySpec{Default: webappv2.DeletionPolicyType(in.Default)}
+	if in.Resources != nil {
+		out.Resources = make(map[string]webappv2.DeletionPolicyType, len(in.Resources))
+		for kind, policy := range in.Resources {
+			out.Resources[kind] = webappv2.DeletionPolicyType(policy)
+		}
+	}
+	return out
+}
+
+func convertDeletionPolicyFrom(in *webappv2.DeletionPolicySpec) *DeletionPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := &DeletionPolicySpec{Default: DeletionPolicyType(in.Default)}
+	if in.Resources != nil {
+		out.Resources = make(map[string]DeletionPolicyType, len(in.Resources))
+		for kind, policy := range in.Resources {
+			out.Resources[kind] = DeletionPolicyType(policy)
+		}
+	}
+	return out
+}
+
+// convertSlice keeps nil slices nil, so that conversions round-trip.
+func convertSlice[In, Out any](in []In, convert func(In) Out) []Out {
+	if in == nil {
+		return nil
+	}
+	out := make([]Out, len(in))
+	for i := range in {
+		out[i] = convert(in[i])
+	}
+	return out
+}
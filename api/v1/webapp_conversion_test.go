@@ -0,0 +1,88 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metafuzzer "k8s.io/apimachinery/pkg/apis/meta/fuzzer"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	webappv2 "github.com/hoon77/crd-operator/api/v2"
+)
+
+const (
+	roundTripIterations = 200
+	// roundTripSeed keeps the fuzzed objects the same from run to run, so
+	// that a failure can be reproduced
+	roundTripSeed = 20251019
+)
+
+func TestWebAppConversionRoundTrip(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := webappv2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	f := fuzzer.FuzzerFor(metafuzzer.Funcs, rand.NewSource(roundTripSeed), serializer.NewCodecFactory(scheme)).
+		NilChance(0.3).MaxDepth(8)
+
+	t.Run("v1 to v2 to v1", func(t *testing.T) {
+		for range roundTripIterations {
+			spoke := &WebApp{}
+			f.Fuzz(spoke)
+
+			hub := &webappv2.WebApp{}
+			if err := spoke.DeepCopy().ConvertTo(hub); err != nil {
+				t.Fatal(err)
+			}
+			got := &WebApp{}
+			if err := got.ConvertFrom(hub); err != nil {
+				t.Fatal(err)
+			}
+			if !apiequality.Semantic.DeepEqual(spoke, got) {
+				t.Fatalf("round trip changed the object (-want +got):\n%s", cmp.Diff(spoke, got))
+			}
+		}
+	})
+
+	t.Run("v2 to v1 to v2", func(t *testing.T) {
+		for range roundTripIterations {
+			hub := &webappv2.WebApp{}
+			f.Fuzz(hub)
+
+			spoke := &WebApp{}
+			if err := spoke.ConvertFrom(hub.DeepCopy()); err != nil {
+				t.Fatal(err)
+			}
+			got := &webappv2.WebApp{}
+			if err := spoke.ConvertTo(got); err != nil {
+				t.Fatal(err)
+			}
+			if !apiequality.Semantic.DeepEqual(hub, got) {
+				t.Fatalf("round trip changed the object (-want +got):\n%s", cmp.Diff(hub, got))
+			}
+		}
+	})
+}
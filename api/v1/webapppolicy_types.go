@@ -0,0 +1,105 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WebAppPolicySpec defines the limits enforced on, and the defaults injected
+// into, the WebApps of the selected namespaces.
+type WebAppPolicySpec struct {
+	// NamespaceSelector selects the namespaces the policy applies to. An
+	// empty selector selects every namespace.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// +optional
+	Limits WebAppPolicyLimits `json:"limits,omitempty"`
+	// +optional
+	Defaults WebAppPolicyDefaults `json:"defaults,omitempty"`
+}
+
+// WebAppPolicyLimits are checked by the validating webhook and the reconciler.
+type WebAppPolicyLimits struct {
+	// AllowedRegistries are the registry hosts (docker.io) or repository
+	// prefixes within one (registry.example.com/team-a) containers may use.
+	// Images without a registry host are from docker.io. Any image is
+	// allowed when empty.
+	// +optional
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+	// MaxReplicas caps spec.replicas and the replicas of every schedule.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+	// RequiredResources must be requested in spec.resources, e.g. cpu, memory.
+	// +optional
+	RequiredResources []corev1.ResourceName `json:"requiredResources,omitempty"`
+	// AllowedIngressDomains are the domains ingress and route hosts must
+	// equal or be a subdomain of. Any host is allowed when empty.
+	// +optional
+	AllowedIngressDomains []string `json:"allowedIngressDomains,omitempty"`
+}
+
+// WebAppPolicyDefaults are merged into the pod template without overriding
+// what the WebApp sets. When several policies select a namespace, they are
+// applied in name order and the first one setting a value wins.
+type WebAppPolicyDefaults struct {
+	// Labels are added to the pods.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Tolerations are added to the pods.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// LivenessProbe of the webapp container.
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+	// ReadinessProbe of the webapp container.
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+}
+
+// WebAppPolicyStatus defines the observed state of WebAppPolicy.
+type WebAppPolicyStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=wapol
+
+// WebAppPolicy is the Schema for the webapppolicies API. It is cluster-scoped
+// so that only platform admins can change it, and selects namespaces by label.
+type WebAppPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WebAppPolicySpec   `json:"spec,omitempty"`
+	Status WebAppPolicyStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WebAppPolicyList contains a list of WebAppPolicy.
+type WebAppPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WebAppPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WebAppPolicy{}, &WebAppPolicyList{})
+}
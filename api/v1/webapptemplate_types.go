@@ -0,0 +1,82 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WebAppTemplateSpec holds the WebApp spec fields shared by the WebApps
+// referencing the template. A WebApp overrides every field it sets itself;
+// configData is merged by key and sidecars by name.
+type WebAppTemplateSpec struct {
+	// ConfigData is merged into spec.configData, the WebApp's keys win.
+	// +optional
+	ConfigData map[string]string `json:"configData,omitempty"`
+	// IngressClassName is used when the WebApp enables an ingress without
+	// a className.
+	// +optional
+	IngressClassName string `json:"ingressClassName,omitempty"`
+	// Sidecars are added before the WebApp's own sidecars. A WebApp sidecar
+	// with the same name replaces the template's.
+	// +optional
+	Sidecars []corev1.Container `json:"sidecars,omitempty"`
+	// +optional
+	Scheduling *SchedulingSpec `json:"scheduling,omitempty"`
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+	// +optional
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+}
+
+// WebAppTemplateStatus defines the observed state of WebAppTemplate.
+type WebAppTemplateStatus struct {
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=watpl
+
+// WebAppTemplate is the Schema for the webapptemplates API. WebApps in the
+// same namespace reference it with spec.templateRef.
+type WebAppTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WebAppTemplateSpec   `json:"spec,omitempty"`
+	Status WebAppTemplateStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WebAppTemplateList contains a list of WebAppTemplate.
+type WebAppTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WebAppTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WebAppTemplate{}, &WebAppTemplateList{})
+}
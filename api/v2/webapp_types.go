@@ -0,0 +1,634 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// WebAppSpec defines the desired state of WebApp.
+type WebAppSpec struct {
+	// TemplateRef names a WebAppTemplate in the WebApp's namespace whose
+	// fields are used where the WebApp sets none.
+	// +optional
+	TemplateRef *WebAppTemplateReference `json:"templateRef,omitempty"`
+
+	// Workload describes the pods: image, replicas, containers and storage.
+	Workload WorkloadSpec `json:"workload"`
+
+	// Networking describes how the pods are exposed and isolated.
+	// +optional
+	Networking NetworkingSpec `json:"networking,omitempty"`
+
+	// Config is mounted into the webapp container as environment variables.
+	// +optional
+	Config ConfigSpec `json:"config,omitempty"`
+
+	// Lifecycle controls how the operator manages the child resources.
+	// +optional
+	Lifecycle LifecycleSpec `json:"lifecycle,omitempty"`
+}
+
+type WorkloadSpec struct {
+	// Image of the webapp container.
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+	// ImagePolicy updates spec.image to the newest tag of its repository
+	// matching the policy. It is ignored unless the operator runs with
+	// --poll-image-tags.
+	// +optional
+	ImagePolicy *ImagePolicySpec `json:"imagePolicy,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas"`
+
+	// InitContainers run to completion before the webapp container starts
+	// (e.g. DB migrations).
+	// +optional
+	InitContainers []corev1.Container `json:"initContainers,omitempty"`
+	// Sidecars run next to the webapp container (e.g. log shippers).
+	// A sidecar with restartPolicy: Always is added as a native sidecar.
+	// +optional
+	Sidecars []corev1.Container `json:"sidecars,omitempty"`
+	// Volumes are added to the pod and can be mounted by every container.
+	// +optional
+	Volumes []corev1.Volume `json:"volumes,omitempty"`
+	// VolumeMounts are mounted into the webapp container.
+	// +optional
+	VolumeMounts []corev1.VolumeMount `json:"volumeMounts,omitempty"`
+
+	// Storage declares PersistentVolumeClaims owned by the WebApp and
+	// mounted into the webapp container.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=32
+	Storage []StorageSpec `json:"storage,omitempty"`
+
+	// Scheduling controls where the webapp pods are placed.
+	// +optional
+	Scheduling *SchedulingSpec `json:"scheduling,omitempty"`
+
+	// PodDisruptionBudget configures the PodDisruptionBudget created while
+	// replicas > 1. Defaults to maxUnavailable: 1.
+	// +optional
+	PodDisruptionBudget *PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+
+	// Resources of the webapp container.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// LivenessProbe of the webapp container.
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+	// ReadinessProbe of the webapp container.
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+
+	// SecurityContext is applied to the webapp container.
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+	// PodSecurityContext is applied to the pod.
+	// +optional
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+
+	// ServiceAccount selects or creates the ServiceAccount the pods run as.
+	// +optional
+	ServiceAccount *ServiceAccountSpec `json:"serviceAccount,omitempty"`
+
+	// Hibernate scales the Deployment to zero. Once it is unset again the
+	// Deployment scales back to replicas, or the count of the active schedule.
+	// +optional
+	Hibernate bool `json:"hibernate,omitempty"`
+
+	// Schedules override replicas or hibernate the WebApp within time
+	// windows. The first active schedule wins.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Schedules []ScheduleSpec `json:"schedules,omitempty"`
+}
+
+type NetworkingSpec struct {
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// Routing selects how the webapp is exposed: an Ingress or a Gateway API
+	// HTTPRoute.
+	// +optional
+	Routing *RoutingSpec `json:"routing,omitempty"`
+
+	// NetworkPolicy isolates the webapp pods.
+	// +optional
+	NetworkPolicy *NetworkPolicySpec `json:"networkPolicy,omitempty"`
+}
+
+type ConfigSpec struct {
+	// Data is stored in the <webapp>-config ConfigMap.
+	// +optional
+	Data map[string]string `json:"data,omitempty"`
+}
+
+type LifecycleSpec struct {
+	// Suspend stops the operator from changing the child resources, e.g. to
+	// hand-edit the Deployment during an incident. Status is still updated.
+	// The webapp.crdlego.com/paused annotation has the same effect.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// DeletionPolicy decides what happens to the child resources when the
+	// WebApp is deleted. Orphan and Retain only hold for the default
+	// background deletion: with foreground deletion the garbage collector
+	// deletes every child before the operator can release it.
+	// +optional
+	DeletionPolicy *DeletionPolicySpec `json:"deletionPolicy,omitempty"`
+
+	// AdoptionPolicy decides whether existing objects with the names of the
+	// WebApp children are taken over.
+	// +kubebuilder:default=Never
+	// +optional
+	AdoptionPolicy AdoptionPolicy `json:"adoptionPolicy,omitempty"`
+}
+
+// WebAppStatus defines the observed state of WebApp.
+type WebAppStatus struct {
+	// Replicas is the desired replica count after hibernation and schedules.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	AvailableReplicas int32 `json:"availableReplicas"`
+
+	// +optional
+	Phase WebAppPhase `json:"phase,omitempty"`
+
+	// CleanupBlockers lists the children that keep a deleted WebApp from
+	// being removed.
+	// +optional
+	CleanupBlockers []string `json:"cleanupBlockers,omitempty"`
+
+	// ActiveSchedule is the name of the schedule currently applied.
+	// +optional
+	ActiveSchedule string `json:"activeSchedule,omitempty"`
+
+	// URL is where the WebApp is reachable through its Ingress or HTTPRoute.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// ServiceEndpoints are the addresses of the owned Service.
+	// +optional
+	ServiceEndpoints []ServiceEndpoint `json:"serviceEndpoints,omitempty"`
+
+	// IngressAddresses are the load balancer addresses of the owned Ingress.
+	// +optional
+	IngressAddresses []string `json:"ingressAddresses,omitempty"`
+
+	// ResolvedImage is spec.image pinned to the digest it resolved to when
+	// digest pinning is enabled, e.g. nginx:1.27@sha256:.... It is resolved
+	// again when spec.image changes.
+	// +optional
+	ResolvedImage string `json:"resolvedImage,omitempty"`
+
+	// ImagePolicy reports the polls of spec.imagePolicy.
+	// +optional
+	ImagePolicy *ImagePolicyStatus `json:"imagePolicy,omitempty"`
+
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ServiceEndpoint is one way of reaching the owned Service.
+type ServiceEndpoint struct {
+	// Type is ClusterIP, NodePort or LoadBalancer.
+	Type corev1.ServiceType `json:"type"`
+	// Address is empty for NodePort endpoints, which are reachable on
+	// every node.
+	// +optional
+	Address string `json:"address,omitempty"`
+	Port    int32  `json:"port"`
+}
+
+// WebAppPhase is a short summary of the WebApp state.
+type WebAppPhase string
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=wa,categories=all
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.workload.image`
+// +kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.replicas`
+// +kubebuilder:printcolumn:name="Available",type=integer,JSONPath=`.status.availableReplicas`
+// +kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.url`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// WebApp is the Schema for the webapps API.
+type WebApp struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WebAppSpec   `json:"spec,omitempty"`
+	Status WebAppStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WebAppList contains a list of WebApp.
+type WebAppList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WebApp `json:"items"`
+}
+
+// +kubebuilder:validation:XValidation:rule="!self.tls || (has(self.host) && size(self.host) > 0)",message="tls requires host"
+type IngressSpec struct {
+	Enabled bool `json:"enabled"`
+	// +optional
+	ClassName string `json:"className,omitempty"`
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^(\*\.)?[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	// +optional
+	Host string `json:"host,omitempty"`
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Port of the Service the Ingress forwards to, defaults to 80.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// +kubebuilder:validation:Pattern=`^/`
+	// +optional
+	RewriteTarget string `json:"rewriteTarget,omitempty"`
+	// +optional
+	TLS bool `json:"tls,omitempty"`
+
+	// HTTP holds the request handling options, rendered to annotations of
+	// the ingress controller selected by the IngressClass.
+	// +optional
+	HTTP IngressHTTPOptions `json:"http,omitempty"`
+
+	// Annotations are copied to the Ingress as-is and take precedence over the
+	// annotations rendered from the typed options.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type IngressHTTPOptions struct {
+	// SSLRedirect redirects plain HTTP requests to HTTPS.
+	// +optional
+	SSLRedirect bool `json:"sslRedirect,omitempty"`
+	// MaxBodySize limits the request body, e.g. "10m".
+	// +optional
+	MaxBodySize string `json:"maxBodySize,omitempty"`
+	// +optional
+	Timeouts *IngressTimeouts `json:"timeouts,omitempty"`
+	// +optional
+	CORS *IngressCORS `json:"cors,omitempty"`
+	// +optional
+	RateLimit *IngressRateLimit `json:"rateLimit,omitempty"`
+	// +optional
+	Auth *IngressAuth `json:"auth,omitempty"`
+	// AllowedSourceRanges only admits clients from these CIDRs.
+	// +optional
+	AllowedSourceRanges []string `json:"allowedSourceRanges,omitempty"`
+}
+
+// IngressTimeouts are the proxy timeouts towards the webapp in seconds.
+type IngressTimeouts struct {
+	// +optional
+	ConnectSeconds int32 `json:"connectSeconds,omitempty"`
+	// +optional
+	ReadSeconds int32 `json:"readSeconds,omitempty"`
+	// +optional
+	SendSeconds int32 `json:"sendSeconds,omitempty"`
+}
+
+type IngressCORS struct {
+	// AllowOrigins defaults to "*".
+	// +optional
+	AllowOrigins []string `json:"allowOrigins,omitempty"`
+	// +optional
+	AllowMethods []string `json:"allowMethods,omitempty"`
+	// +optional
+	AllowHeaders []string `json:"allowHeaders,omitempty"`
+	// +optional
+	AllowCredentials bool `json:"allowCredentials,omitempty"`
+}
+
+type IngressRateLimit struct {
+	// RequestsPerSecond allowed from a single client IP.
+	// +kubebuilder:validation:Minimum=1
+	RequestsPerSecond int32 `json:"requestsPerSecond"`
+}
+
+// IngressAuth enables either basic or external authentication.
+type IngressAuth struct {
+	// +optional
+	Basic *IngressBasicAuth `json:"basic,omitempty"`
+	// +optional
+	External *IngressExternalAuth `json:"external,omitempty"`
+}
+
+type IngressBasicAuth struct {
+	// SecretName of a Secret with an htpasswd "auth" key.
+	SecretName string `json:"secretName"`
+	// +optional
+	Realm string `json:"realm,omitempty"`
+}
+
+type IngressExternalAuth struct {
+	// URL of the authentication service.
+	URL string `json:"url"`
+	// SignInURL redirects unauthenticated requests.
+	// +optional
+	SignInURL string `json:"signInURL,omitempty"`
+	// ResponseHeaders are copied from the auth response to the upstream request.
+	// +optional
+	ResponseHeaders []string `json:"responseHeaders,omitempty"`
+}
+
+// StorageSpec describes a PersistentVolumeClaim named <webapp>-<name>.
+// storageClassName and accessModes cannot be changed on an existing
+// PersistentVolumeClaim, so they are immutable here too.
+// +kubebuilder:validation:XValidation:rule="has(self.storageClassName) == has(oldSelf.storageClassName) && (!has(self.storageClassName) || self.storageClassName == oldSelf.storageClassName)",message="storageClassName is immutable"
+// +kubebuilder:validation:XValidation:rule="has(self.accessModes) == has(oldSelf.accessModes) && (!has(self.accessModes) || self.accessModes == oldSelf.accessModes)",message="accessModes is immutable"
+type StorageSpec struct {
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+	// +kubebuilder:validation:Pattern=`^/`
+	MountPath string            `json:"mountPath"`
+	Size      resource.Quantity `json:"size"`
+	// +kubebuilder:validation:MaxLength=253
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+	// AccessModes defaults to ReadWriteOnce.
+	// +kubebuilder:validation:MaxItems=4
+	// +kubebuilder:validation:items:Enum=ReadWriteOnce;ReadOnlyMany;ReadWriteMany;ReadWriteOncePod
+	// +optional
+	AccessModes []corev1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+	// Retain keeps the PersistentVolumeClaim when the WebApp is deleted.
+	// +optional
+	Retain bool `json:"retain,omitempty"`
+}
+
+// SchedulingSpec holds the pod placement hints copied into the pod template.
+type SchedulingSpec struct {
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// SpreadAcrossZones adds zone and hostname spread constraints selecting
+	// the pods by their app label. Constraints declared in
+	// topologySpreadConstraints for the same topology key take precedence.
+	// +optional
+	SpreadAcrossZones bool `json:"spreadAcrossZones,omitempty"`
+}
+
+// PodDisruptionBudgetSpec sets either minAvailable or maxUnavailable.
+// +kubebuilder:validation:XValidation:rule="!(has(self.minAvailable) && has(self.maxUnavailable))",message="minAvailable and maxUnavailable are mutually exclusive"
+type PodDisruptionBudgetSpec struct {
+	// Enabled defaults to true.
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// ServiceAccountSpec either references an existing ServiceAccount or lets the
+// operator create one owned by the WebApp.
+// +kubebuilder:validation:XValidation:rule="!has(self.rules) || size(self.rules) == 0 || (has(self.create) && self.create) || (has(self.name) && size(self.name) > 0 && self.name != 'default')",message="rules require a created or named ServiceAccount other than default"
+type ServiceAccountSpec struct {
+	// Name of the ServiceAccount. Defaults to the WebApp name when create is true.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Create makes the operator create and own the ServiceAccount.
+	// +optional
+	Create bool `json:"create,omitempty"`
+	// Annotations of the created ServiceAccount (e.g. workload identity bindings).
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// AutomountServiceAccountToken is set on the pod and the created ServiceAccount.
+	// +optional
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
+	// Rules creates a Role with these rules bound to the ServiceAccount. The
+	// ServiceAccount must be created or named, rules are never granted to the
+	// namespace default ServiceAccount. The operator can only grant
+	// permissions it holds itself.
+	// +optional
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+}
+
+// NetworkPolicySpec describes the NetworkPolicy generated for the webapp pods.
+// Ingress is only allowed from the ingress controller namespace and the listed
+// peers. Egress is restricted as soon as egress rules are declared or
+// defaultDeny is set; DNS to kube-system is always allowed in that case.
+type NetworkPolicySpec struct {
+	Enabled bool `json:"enabled"`
+	// DefaultDeny denies all egress that is not declared in egress.
+	// +optional
+	DefaultDeny bool `json:"defaultDeny,omitempty"`
+	// IngressControllerNamespace defaults to ingress-nginx.
+	// +optional
+	IngressControllerNamespace string `json:"ingressControllerNamespace,omitempty"`
+	// AllowFromNamespaces allows ingress from every pod in these namespaces.
+	// +optional
+	AllowFromNamespaces []string `json:"allowFromNamespaces,omitempty"`
+	// AllowFrom allows ingress from these peers.
+	// +optional
+	AllowFrom []networkingv1.NetworkPolicyPeer `json:"allowFrom,omitempty"`
+	// +optional
+	Egress []NetworkPolicyEgressRule `json:"egress,omitempty"`
+}
+
+// NetworkPolicyEgressRule allows egress to CIDRs and the pods of other
+// WebApps on the given ports.
+type NetworkPolicyEgressRule struct {
+	// +optional
+	CIDRs []string `json:"cidrs,omitempty"`
+	// WebApps allows egress to the pods of these WebApps. Other workloads
+	// are reached through cidrs, as their labels are not known.
+	// +optional
+	WebApps []WebAppReference `json:"webApps,omitempty"`
+	// Ports restricts the rule to these ports; all ports when empty.
+	// +optional
+	Ports []networkingv1.NetworkPolicyPort `json:"ports,omitempty"`
+}
+
+// WebAppReference selects the pods of a WebApp, i.e. the pods labeled
+// app=<name> in its namespace.
+type WebAppReference struct {
+	Name string `json:"name"`
+	// Namespace defaults to the WebApp namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// RoutingType is the kind of object used to expose the webapp.
+// +kubebuilder:validation:Enum=Ingress;Gateway
+type RoutingType string
+
+// +kubebuilder:validation:XValidation:rule="self.type != 'Gateway' || has(self.gateway)",message="gateway is required when type is Gateway"
+type RoutingSpec struct {
+	// +kubebuilder:default=Ingress
+	Type RoutingType `json:"type"`
+	// Gateway configures the HTTPRoute when type is Gateway.
+	// +optional
+	Gateway *GatewayRouteSpec `json:"gateway,omitempty"`
+}
+
+// GatewayRouteSpec describes the HTTPRoute. Hostnames and rules default to
+// networking.ingress host, path and rewriteTarget, so switching an existing
+// WebApp from Ingress to Gateway only needs parentRefs.
+type GatewayRouteSpec struct {
+	// +kubebuilder:validation:MinItems=1
+	ParentRefs []GatewayParentReference `json:"parentRefs"`
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+	// +optional
+	Rules []GatewayRouteRule `json:"rules,omitempty"`
+}
+
+// GatewayParentReference references the Gateway the HTTPRoute attaches to.
+type GatewayParentReference struct {
+	Name string `json:"name"`
+	// Namespace defaults to the WebApp namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// SectionName selects a listener of the Gateway.
+	// +optional
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+type GatewayRouteRule struct {
+	// Path defaults to "/".
+	// +optional
+	Path string `json:"path,omitempty"`
+	// +kubebuilder:validation:Enum=PathPrefix;Exact
+	// +kubebuilder:default=PathPrefix
+	// +optional
+	PathType string `json:"pathType,omitempty"`
+	// Headers must all match exactly.
+	// +optional
+	Headers map[string]string `json:"headers,omitempty"`
+	// RewritePath replaces the matched path prefix before forwarding.
+	// +optional
+	RewritePath string `json:"rewritePath,omitempty"`
+}
+
+// AdoptionPolicy is how the WebApp treats an existing object with the name of
+// one of its children. Never leaves it alone, IfUnowned takes it over when no
+// other controller owns it, Force takes it over from its current controller.
+// +kubebuilder:validation:Enum=Never;IfUnowned;Force
+type AdoptionPolicy string
+
+// ImagePolicySpec selects the tags spec.image is updated to. Exactly one of
+// semver and pattern is set.
+// +kubebuilder:validation:XValidation:rule="has(self.semver) != has(self.pattern)",message="exactly one of semver or pattern is required"
+type ImagePolicySpec struct {
+	// Semver is a version range such as ">=1.2.0 <2.0.0" or "1.x". Tags
+	// that are not versions, and pre-releases, are skipped.
+	// +optional
+	Semver string `json:"semver,omitempty"`
+	// Pattern is a regular expression the whole tag must match. Matching
+	// tags are ordered by the first capture group, or the whole tag,
+	// numerically when both are integers and lexically otherwise.
+	// +optional
+	Pattern string `json:"pattern,omitempty"`
+	// Interval between two polls of the registry, defaults to 5m and is at
+	// least 1m.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// ImagePolicyStatus records the polls and updates made for spec.imagePolicy.
+type ImagePolicyStatus struct {
+	// LatestImage is the newest image matching the policy at the last poll.
+	// +optional
+	LatestImage string `json:"latestImage,omitempty"`
+	// PreviousImage is spec.image before the last update, to roll back to.
+	// +optional
+	PreviousImage string `json:"previousImage,omitempty"`
+	// LastPollTime is when the tags were last listed.
+	// +optional
+	LastPollTime *metav1.Time `json:"lastPollTime,omitempty"`
+	// LastUpdateTime is when spec.image was last updated.
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// WebAppTemplateReference names a WebAppTemplate in the same namespace.
+type WebAppTemplateReference struct {
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// ScheduleSpec is a recurring window, opened by the start cron expression
+// and closed by the end one, e.g. start "0 20 * * 1-5", end "0 8 * * 1-5".
+// +kubebuilder:validation:XValidation:rule="has(self.replicas) || self.hibernate",message="a schedule sets replicas or hibernate"
+type ScheduleSpec struct {
+	Name string `json:"name"`
+	// Start is a standard 5-field cron expression.
+	Start string `json:"start"`
+	// End is a standard 5-field cron expression.
+	End string `json:"end"`
+	// TimeZone is an IANA time zone name, defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Replicas replaces workload.replicas within the window.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Hibernate scales the WebApp to zero within the window.
+	// +optional
+	Hibernate bool `json:"hibernate,omitempty"`
+}
+
+// DeletionPolicyType is what the finalizer does with a child resource.
+// Delete removes it, Orphan strips the WebApp owner reference and leaves it
+// running, Retain does the same and marks it so that a WebApp re-created
+// with the same name takes it over.
+// +kubebuilder:validation:Enum=Delete;Orphan;Retain
+type DeletionPolicyType string
+
+type DeletionPolicySpec struct {
+	// Default applies to every kind not listed in resources.
+	// +kubebuilder:default=Delete
+	// +optional
+	Default DeletionPolicyType `json:"default,omitempty"`
+	// Resources overrides the policy per child kind, e.g. {"Deployment": "Orphan"}.
+	// +optional
+	Resources map[string]DeletionPolicyType `json:"resources,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WebApp{}, &WebAppList{})
+}
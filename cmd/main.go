@@ -18,19 +18,31 @@ package main
 
 import (
 	"flag"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	webappv2 "github.com/hoon77/crd-operator/api/v2"
 	"github.com/hoon77/crd-operator/internal/controller"
+	"github.com/hoon77/crd-operator/internal/pkg/registry"
+	"github.com/hoon77/crd-operator/internal/pkg/sharding"
+	webhookwebappv1 "github.com/hoon77/crd-operator/internal/webhook/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -42,15 +54,49 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(webappv1.AddToScheme(scheme))
+	utilruntime.Must(webappv2.AddToScheme(scheme))
+	utilruntime.Must(gatewayv1.Install(scheme))
 	// +kubebuilder:scaffold:scheme
 }
 
 // nolint:gocyclo
 func main() {
 	var enableLeaderElection bool
+	var hardenedSecurityDefaults bool
+	var cleanupPropagationPolicy string
+	var watchNamespaces string
+	var webappLabelSelector string
+	var enableSharding bool
+	var shardLeaseNamespace string
+	var allowedRegistries string
+	var resolveImageDigests bool
+	var pollImageTags bool
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&hardenedSecurityDefaults, "hardened-security-defaults", false,
+		"Apply a restricted security profile (runAsNonRoot, readOnlyRootFilesystem, drop ALL capabilities, "+
+			"seccomp RuntimeDefault) to WebApp pods unless the WebApp overrides it.")
+	flag.StringVar(&cleanupPropagationPolicy, "cleanup-propagation-policy", string(metav1.DeletePropagationBackground),
+		"Propagation policy used when deleting WebApp children during finalization: Background, Foreground or Orphan.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Comma-separated namespaces to watch. All namespaces are watched when empty.")
+	flag.StringVar(&webappLabelSelector, "webapp-label-selector", "",
+		"Only reconcile WebApps matching this label selector, e.g. team=payments.")
+	flag.BoolVar(&enableSharding, "sharding", false,
+		"Split WebApps across every replica by hash range, coordinated through Leases. "+
+			"Cannot be combined with --leader-elect.")
+	flag.StringVar(&shardLeaseNamespace, "shard-lease-namespace", os.Getenv("POD_NAMESPACE"),
+		"Namespace of the shard Leases. Defaults to the POD_NAMESPACE environment variable.")
+	flag.StringVar(&allowedRegistries, "allowed-registries", "",
+		"Comma-separated registries or repository prefixes WebApp images must come from, "+
+			"e.g. docker.io/library,ghcr.io/example. Every registry is allowed when empty.")
+	flag.BoolVar(&resolveImageDigests, "resolve-image-digests", false,
+		"Resolve the WebApp image tag to its digest once per image change and deploy image@sha256:..., "+
+			"so that a moved tag does not change the running pods. Only public repositories can be resolved.")
+	flag.BoolVar(&pollImageTags, "poll-image-tags", false,
+		"Follow spec.imagePolicy by polling the tags of the WebApp image repositories and updating spec.image. "+
+			"Only public repositories can be polled.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -58,6 +104,24 @@ func main() {
 	flag.Parse()
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	switch metav1.DeletionPropagation(cleanupPropagationPolicy) {
+	case metav1.DeletePropagationBackground, metav1.DeletePropagationForeground, metav1.DeletePropagationOrphan:
+	default:
+		setupLog.Error(nil, "invalid --cleanup-propagation-policy", "policy", cleanupPropagationPolicy)
+		os.Exit(1)
+	}
+
+	if enableSharding && (enableLeaderElection || shardLeaseNamespace == "") {
+		setupLog.Error(nil, "--sharding needs --shard-lease-namespace and cannot be combined with --leader-elect")
+		os.Exit(1)
+	}
+
+	cacheOptions, err := newCacheOptions(watchNamespaces, webappLabelSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid --webapp-label-selector", "selector", webappLabelSelector)
+		os.Exit(1)
+	}
+
 	// If the certificate is not specified, controller-runtime will automatically
 	// generate self-signed certificates for the metrics server. While convenient for development and testing,
 	// this setup is not recommended for production.
@@ -69,6 +133,7 @@ func main() {
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:           scheme,
+		Cache:            cacheOptions,
 		LeaderElection:   enableLeaderElection,
 		LeaderElectionID: "6219839f.crdlego.com",
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
@@ -88,13 +153,51 @@ func main() {
 		os.Exit(1)
 	}
 
+	registryClient := &registry.Client{HTTPClient: &http.Client{Timeout: 30 * time.Second}}
+	var imageResolver registry.Resolver
+	if resolveImageDigests {
+		imageResolver = registryClient
+	}
+	var tagLister registry.TagLister
+	if pollImageTags {
+		tagLister = registryClient
+	}
+
+	var shards *sharding.Coordinator
+	if enableSharding {
+		// the pod name is unique per replica
+		identity, err := os.Hostname()
+		if err != nil {
+			setupLog.Error(err, "unable to get the shard identity")
+			os.Exit(1)
+		}
+		shards = sharding.NewCoordinator(mgr.GetClient(), mgr.GetAPIReader(), shardLeaseNamespace, identity)
+		if err := mgr.Add(shards); err != nil {
+			setupLog.Error(err, "unable to add the shard coordinator")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controller.WebAppReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		HardenedSecurityDefaults: hardenedSecurityDefaults,
+		CleanupPropagationPolicy: metav1.DeletionPropagation(cleanupPropagationPolicy),
+		Shards:                   shards,
+		AllowedRegistries:        splitList(allowedRegistries),
+		ImageResolver:            imageResolver,
+		TagLister:                tagLister,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "WebApp")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookwebappv1.SetupWebAppWebhookWithManager(mgr, splitList(allowedRegistries)); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "WebApp")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -112,3 +215,37 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// newCacheOptions restricts the manager cache to the watched namespaces and
+// the WebApps matching the selector. Cluster-scoped objects such as
+// Namespaces and IngressClasses are cached regardless of the namespaces.
+func newCacheOptions(watchNamespaces, webappLabelSelector string) (cache.Options, error) {
+	var opts cache.Options
+	for _, namespace := range splitList(watchNamespaces) {
+		if opts.DefaultNamespaces == nil {
+			opts.DefaultNamespaces = map[string]cache.Config{}
+		}
+		opts.DefaultNamespaces[namespace] = cache.Config{}
+	}
+	if webappLabelSelector != "" {
+		selector, err := labels.Parse(webappLabelSelector)
+		if err != nil {
+			return opts, err
+		}
+		opts.ByObject = map[client.Object]cache.ByObject{
+			&webappv1.WebApp{}: {Label: selector},
+		}
+	}
+	return opts, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
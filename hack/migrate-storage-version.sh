@@ -0,0 +1,18 @@
+#!/usr/bin/env bash
+# Rewrites every WebApp in the storage version of the CRD and then removes the
+# other versions from status.storedVersions, so that an old version can be
+# dropped from the CRD. Run it after switching the storage version and once the
+# conversion webhook is serving. Re-run it if a replace fails on a conflict.
+set -euo pipefail
+
+CRD=webapps.webapp.crdlego.com
+
+storage=$(kubectl get crd "${CRD}" -o jsonpath='{.spec.versions[?(@.storage==true)].name}')
+echo "storage version: ${storage}"
+
+# replacing an object makes the API server encode it again in the storage version
+kubectl get "${CRD}" --all-namespaces -o json | kubectl replace -f -
+
+kubectl patch crd "${CRD}" --subresource=status --type=merge \
+  -p "{\"status\":{\"storedVersions\":[\"${storage}\"]}}"
+kubectl get crd "${CRD}" -o jsonpath='{.status.storedVersions}{"\n"}'
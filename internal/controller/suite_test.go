@@ -19,7 +19,9 @@ package controller
 import (
 	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -31,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	webappv1 "github.com/hoon77/crd-operator/api/v1"
 	// +kubebuilder:scaffold:imports
@@ -61,12 +64,17 @@ var _ = BeforeSuite(func() {
 	var err error
 	err = webappv1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
+	err = gatewayv1.Install(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
 
 	// +kubebuilder:scaffold:scheme
 
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
-		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd", "bases")},
+		CRDDirectoryPaths: []string{
+			filepath.Join("..", "..", "config", "crd", "bases"),
+			getGatewayAPICRDDir(),
+		},
 		ErrorIfCRDPathMissing: true,
 	}
 
@@ -114,3 +122,12 @@ func getFirstFoundEnvTestBinaryDir() string {
 	}
 	return ""
 }
+
+// getGatewayAPICRDDir returns the standard Gateway API CRDs shipped with the
+// sigs.k8s.io/gateway-api module, so that HTTPRoutes can be served by envtest
+// without vendoring the CRD manifests.
+func getGatewayAPICRDDir() string {
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "sigs.k8s.io/gateway-api").Output()
+	Expect(err).NotTo(HaveOccurred())
+	return filepath.Join(strings.TrimSpace(string(out)), "config", "crd", "standard")
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/resources"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// claim makes sure an existing child is controlled by the WebApp before it is
+// updated. Objects the WebApp does not control are adopted according to
+// spec.adoptionPolicy; objects retained by a previous WebApp with the same
+// name are always adopted. When the object cannot be adopted, claim returns
+// false and a message describing the conflict.
+func (r *WebAppReconciler) claim(ctx context.Context, webapp *webappv1.WebApp, obj client.Object) (bool, string, error) {
+	if metav1.IsControlledBy(obj, webapp) {
+		return true, "", nil
+	}
+
+	policy := webapp.Spec.AdoptionPolicy
+	if policy == "" {
+		policy = webappv1.AdoptionPolicyNever
+	}
+	retained := obj.GetAnnotations()[resources.WebAppRetainedBy] == webapp.Name
+	owner := metav1.GetControllerOf(obj)
+	switch {
+	case owner == nil && (retained || policy != webappv1.AdoptionPolicyNever):
+	case owner != nil && policy == webappv1.AdoptionPolicyForce:
+		var refs []metav1.OwnerReference
+		for _, ref := range obj.GetOwnerReferences() {
+			if ref.Controller == nil || !*ref.Controller {
+				refs = append(refs, ref)
+			}
+		}
+		obj.SetOwnerReferences(refs)
+	case owner != nil:
+		return false, fmt.Sprintf("%s is controlled by %s/%s", r.describeChild(obj), owner.Kind, owner.Name), nil
+	default:
+		return false, fmt.Sprintf("%s exists and is not owned by the WebApp (adoptionPolicy %s)", r.describeChild(obj), policy), nil
+	}
+
+	logf.FromContext(ctx).Info("Adopt child", "Child", r.describeChild(obj), "AdoptionPolicy", policy)
+	annotations := obj.GetAnnotations()
+	delete(annotations, resources.WebAppRetainedBy)
+	obj.SetAnnotations(annotations)
+	if err := controllerutil.SetControllerReference(webapp, obj, r.Scheme); err != nil {
+		return false, "", err
+	}
+	if err := r.Update(ctx, obj); err != nil {
+		return false, "", err
+	}
+	return true, "", nil
+}
+
+// setResourcesOwnedCondition records the children that could not be adopted.
+func setResourcesOwnedCondition(webapp *webappv1.WebApp, conflicts []string) {
+	condition := metav1.Condition{
+		Type:               webappv1.ConditionResourcesOwned,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Owned",
+		Message:            "All child resources are controlled by the WebApp",
+		ObservedGeneration: webapp.Generation,
+	}
+	if len(conflicts) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "OwnershipConflict"
+		condition.Message = strings.Join(conflicts, "; ")
+	}
+	meta.SetStatusCondition(&webapp.Status.Conditions, condition)
+}
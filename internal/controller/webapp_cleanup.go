@@ -0,0 +1,213 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/resources"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	cleanupMinBackoff = time.Second
+	cleanupMaxBackoff = time.Minute
+)
+
+// childObjectLists returns a list type for every kind a WebApp can own.
+func (r *WebAppReconciler) childObjectLists() []client.ObjectList {
+	lists := []client.ObjectList{
+		&appsv1.DeploymentList{},
+		&corev1.ServiceList{},
+		&corev1.ConfigMapList{},
+		&networkingv1.IngressList{},
+		&corev1.PersistentVolumeClaimList{},
+		&policyv1.PodDisruptionBudgetList{},
+		&networkingv1.NetworkPolicyList{},
+		&corev1.ServiceAccountList{},
+		&rbacv1.RoleList{},
+		&rbacv1.RoleBindingList{},
+	}
+	if r.GatewayAPIAvailable {
+		lists = append(lists, &gatewayv1.HTTPRouteList{})
+	}
+	return lists
+}
+
+// findChildren returns every object in the WebApp namespace whose controller
+// reference points to the WebApp, regardless of its name.
+func (r *WebAppReconciler) findChildren(ctx context.Context, webapp *webappv1.WebApp) ([]client.Object, error) {
+	var children []client.Object
+	for _, list := range r.childObjectLists() {
+		if err := r.List(ctx, list, client.InNamespace(webapp.Namespace)); err != nil {
+			return nil, err
+		}
+		if err := meta.EachListItem(list, func(item runtime.Object) error {
+			child := item.(client.Object)
+			if metav1.IsControlledBy(child, webapp) {
+				children = append(children, child)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return children, nil
+}
+
+// finalize deletes the children of a deleted WebApp and removes the finalizer
+// once none of them is left. Children that are still terminating are reported
+// in status.cleanupBlockers and the WebApp is requeued with a backoff that
+// grows with the time since the deletion started.
+//
+// Orphan and Retain children are released by removing the controller
+// reference. That races the garbage collector on foreground deletion
+// (kubectl delete --cascade=foreground), which deletes the dependents of the
+// WebApp as soon as it is marked for deletion, so such children may be lost.
+func (r *WebAppReconciler) finalize(ctx context.Context, webapp *webappv1.WebApp) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	if controllerutil.ContainsFinalizer(webapp, metav1.FinalizerDeleteDependents) {
+		log.Info("WebApp is deleted in the foreground, the garbage collector may delete children before they are released")
+	}
+
+	children, err := r.findChildren(ctx, webapp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var errs []error
+	for _, child := range children {
+		if child.GetDeletionTimestamp() != nil {
+			continue
+		}
+		policy := resources.DeletionPolicyFor(webapp, r.childKind(child), child.GetName())
+		if policy != webappv1.DeletionPolicyDelete {
+			log.Info("Release child", "Child", r.describeChild(child), "DeletionPolicy", policy)
+			if err := r.releaseChild(ctx, webapp, child, policy); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := r.Delete(ctx, child, client.PropagationPolicy(r.cleanupPropagationPolicy())); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("delete %s: %w", r.describeChild(child), err))
+		}
+	}
+
+	// objects without finalizers are gone right away, only wait for the rest
+	remaining, err := r.findChildren(ctx, webapp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(remaining) == 0 && len(errs) == 0 {
+		controllerutil.RemoveFinalizer(webapp, resources.WebAppFinalizer)
+		return ctrl.Result{}, r.Update(ctx, webapp)
+	}
+
+	var blockers []string
+	for _, child := range remaining {
+		blockers = append(blockers, r.describeChild(child)+": waiting for deletion")
+	}
+	for _, err := range errs {
+		blockers = append(blockers, err.Error())
+	}
+	webapp.Status.Phase = webappv1.WebAppPhaseTerminating
+	meta.SetStatusCondition(&webapp.Status.Conditions, metav1.Condition{
+		Type:               webappv1.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Terminating",
+		Message:            "The WebApp is being deleted",
+		ObservedGeneration: webapp.Generation,
+	})
+	webapp.Status.CleanupBlockers = blockers
+	if err := r.Status().Update(ctx, webapp); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if len(errs) > 0 {
+		// the controller's rate limiter backs off on errors
+		return ctrl.Result{}, utilerrors.NewAggregate(errs)
+	}
+	backoff := cleanupBackoff(time.Since(webapp.DeletionTimestamp.Time))
+	log.Info("Waiting for children to be deleted", "Blockers", len(blockers), "RequeueAfter", backoff)
+	return ctrl.Result{RequeueAfter: backoff}, nil
+}
+
+// releaseChild removes the WebApp controller reference, so that neither the
+// finalizer nor the garbage collector deletes the child. Retained children
+// also record the WebApp name.
+func (r *WebAppReconciler) releaseChild(ctx context.Context, webapp *webappv1.WebApp, child client.Object, policy webappv1.DeletionPolicyType) error {
+	if err := controllerutil.RemoveControllerReference(webapp, child, r.Scheme); err != nil {
+		return err
+	}
+	if policy == webappv1.DeletionPolicyRetain {
+		annotations := child.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[resources.WebAppRetainedBy] = webapp.Name
+		child.SetAnnotations(annotations)
+	}
+	if err := r.Update(ctx, child); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("release %s: %w", r.describeChild(child), err)
+	}
+	return nil
+}
+
+func (r *WebAppReconciler) cleanupPropagationPolicy() metav1.DeletionPropagation {
+	if r.CleanupPropagationPolicy == "" {
+		return metav1.DeletePropagationBackground
+	}
+	return r.CleanupPropagationPolicy
+}
+
+// childKind returns the Kind of a child, e.g. "Deployment".
+func (r *WebAppReconciler) childKind(child client.Object) string {
+	gvk, err := apiutil.GVKForObject(child, r.Scheme)
+	if err != nil {
+		return ""
+	}
+	return gvk.Kind
+}
+
+// describeChild returns "<Kind>/<name>" for status messages.
+func (r *WebAppReconciler) describeChild(child client.Object) string {
+	return r.childKind(child) + "/" + child.GetName()
+}
+
+// cleanupBackoff waits half the time the deletion has been running,
+// bounded by cleanupMinBackoff and cleanupMaxBackoff.
+func cleanupBackoff(elapsed time.Duration) time.Duration {
+	return min(max(elapsed/2, cleanupMinBackoff), cleanupMaxBackoff)
+}
@@ -18,28 +18,70 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/registry"
 	"github.com/hoon77/crd-operator/internal/pkg/resources"
+	"github.com/hoon77/crd-operator/internal/pkg/sharding"
 	"github.com/hoon77/crd-operator/internal/pkg/utils"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
 	"reflect"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	"strings"
+	"time"
 )
 
 // WebAppReconciler reconciles a WebApp object
 type WebAppReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// GatewayAPIAvailable is set by SetupWithManager when the HTTPRoute CRD is installed.
+	GatewayAPIAvailable bool
+
+	// CleanupPropagationPolicy is used to delete the children of a deleted
+	// WebApp. Defaults to Background.
+	CleanupPropagationPolicy metav1.DeletionPropagation
+
+	// HardenedSecurityDefaults applies a PSA "restricted" compatible security
+	// profile to every generated pod, unless the WebApp overrides it.
+	HardenedSecurityDefaults bool
+
+	// Clock evaluates spec.schedules. Defaults to the real clock.
+	Clock clock.PassiveClock
+
+	// Shards restricts the reconciler to the WebApps of this replica's
+	// shard. Nil reconciles every WebApp.
+	Shards *sharding.Coordinator
+
+	// AllowedRegistries rejects WebApps with images outside these registries
+	// or repository prefixes. Empty allows every registry.
+	AllowedRegistries []string
+
+	// ImageResolver pins spec.image to its digest. Nil deploys the image as
+	// written.
+	ImageResolver registry.Resolver
+
+	// TagLister polls the tags for spec.imagePolicy. Nil ignores image
+	// policies and reports them as ImageUpToDate=Unknown.
+	TagLister registry.TagLister
 }
 
 // +kubebuilder:rbac:groups=webapp.crdlego.com,resources=webapps,verbs=get;list;watch;create;update;patch;delete
@@ -47,8 +89,16 @@ type WebAppReconciler struct {
 // +kubebuilder:rbac:groups=webapp.crdlego.com,resources=webapps/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingressclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -72,49 +122,88 @@ func (r *WebAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, err
 	}
 
+	// another replica owns this webapp
+	if r.Shards != nil && !r.Shards.Owns(req.NamespacedName) {
+		return ctrl.Result{}, nil
+	}
+
 	// detect webapp deletion
 	if !webapp.DeletionTimestamp.IsZero() {
 		klog.Infof("Webapp %s/%s is being deleted. Cleaning up...", webapp.Namespace, webapp.Name)
+		if !controllerutil.ContainsFinalizer(&webapp, resources.WebAppFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		return r.finalize(ctx, &webapp)
+	}
 
-		// delete deployment
-		_ = r.Delete(ctx, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
-			Namespace: webapp.Namespace,
-			Name:      webapp.Name,
-		}})
-		// delete service
-		_ = r.Delete(ctx, &corev1.Service{ObjectMeta: metav1.ObjectMeta{
-			Namespace: webapp.Namespace,
-			Name:      webapp.Name,
-		}})
-		// delete configmap
-		_ = r.Delete(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
-			Namespace: webapp.Namespace,
-			Name:      webapp.Name + resources.ConfigMapSuffix,
-		}})
-
-		// delete ingress
-		_ = r.Delete(ctx, &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{
-			Namespace: webapp.Namespace,
-			Name:      webapp.Name + resources.IngressTLSSecretNameMaSuffix,
-		}})
-
-		// remove finalizer
-		controllerutil.RemoveFinalizer(&webapp, resources.WebAppFinalizer)
+	// add finalizer to webapp
+	if !controllerutil.ContainsFinalizer(&webapp, resources.WebAppFinalizer) {
+		controllerutil.AddFinalizer(&webapp, resources.WebAppFinalizer)
 		if err := r.Update(ctx, &webapp); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{}, nil
 	}
 
-	// add finalizer to webapp
-	if !controllerutil.ContainsFinalizer(&webapp, resources.WebAppFinalizer) {
-		controllerutil.AddFinalizer(&webapp, resources.WebAppFinalizer)
+	// record the owning shard
+	if r.Shards != nil && webapp.Labels[sharding.ShardLabel] != r.Shards.Identity {
+		if webapp.Labels == nil {
+			webapp.Labels = map[string]string{}
+		}
+		webapp.Labels[sharding.ShardLabel] = r.Shards.Identity
 		if err := r.Update(ctx, &webapp); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{}, nil
 	}
 
+	oldStatus := webapp.Status.DeepCopy()
+
+	// leave the children alone, only report their state
+	if suspended(&webapp) {
+		return ctrl.Result{}, r.reconcileSuspended(ctx, &webapp, oldStatus)
+	}
+	// fill in the fields left unset from the WebAppTemplate, in memory only
+	if ok, err := r.applyTemplate(ctx, &webapp, oldStatus); err != nil || !ok {
+		return ctrl.Result{}, err
+	}
+
+	// enforce the WebAppPolicies of the namespace before touching the children
+	policies, err := r.policiesFor(ctx, &webapp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if violations := resources.PolicyViolations(&webapp, policies); len(violations) > 0 {
+		return ctrl.Result{}, r.reportPolicyViolations(ctx, &webapp, oldStatus, violations)
+	}
+	setPolicyCompliantCondition(&webapp, policies)
+
+	// follow spec.imagePolicy, the next reconcile rolls out an updated image
+	updated, nextPoll, err := r.updateImage(ctx, &webapp)
+	if err != nil || updated {
+		return ctrl.Result{}, err
+	}
+
+	// pin the image to its digest in the in-memory spec only
+	if ok, err := r.resolveImage(ctx, &webapp, oldStatus); err != nil || !ok {
+		return ctrl.Result{}, err
+	}
+
+	// hand edits made while suspended do not change the template-hash, so
+	// the Deployment is rewritten once on resume
+	resuming := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionSuspended) != nil
+	meta.RemoveStatusCondition(&webapp.Status.Conditions, webappv1.ConditionSuspended)
+
+	// apply the active schedule to the in-memory spec only
+	activeSchedule, nextTransition, err := resources.ApplySchedules(&webapp, r.now())
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	webapp.Status.ActiveSchedule = activeSchedule
+
+	// children with the WebApp's names that it could not adopt
+	var conflicts []string
+
 	// Create configmap
 	createConfigmap := resources.BuildConfigMap(&webapp)
 	if err := utils.SetOwnerRefence(&webapp, createConfigmap, r.Scheme); err != nil {
@@ -122,13 +211,17 @@ func (r *WebAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	}
 
 	foundConfigmap := &corev1.ConfigMap{}
-	err := r.Get(ctx, types.NamespacedName{Namespace: createConfigmap.Namespace, Name: createConfigmap.Name}, foundConfigmap)
+	err = r.Get(ctx, types.NamespacedName{Namespace: createConfigmap.Namespace, Name: createConfigmap.Name}, foundConfigmap)
 	if err != nil && errors.IsNotFound(err) {
 		if err := r.Create(ctx, createConfigmap); err != nil {
 			return ctrl.Result{}, err
 		}
 	} else if err != nil {
 		return ctrl.Result{}, err
+	} else if owned, conflict, err := r.claim(ctx, &webapp, foundConfigmap); err != nil {
+		return ctrl.Result{}, err
+	} else if !owned {
+		conflicts = append(conflicts, conflict)
 	} else {
 		if !reflect.DeepEqual(foundConfigmap.Data, createConfigmap.Data) {
 			log.Info("New Configmap Data", "Data", createConfigmap.Data)
@@ -139,48 +232,144 @@ func (r *WebAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 	}
 
+	// Create persistentvolumeclaims
+	storageConflicts, err := r.reconcileStorage(ctx, &webapp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	conflicts = append(conflicts, storageConflicts...)
+
+	// Create serviceaccount, role and rolebinding
+	rbacConflicts, err := r.reconcileServiceAccount(ctx, &webapp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	conflicts = append(conflicts, rbacConflicts...)
+
 	// Create deployment
-	createDeploy := resources.BuildDeployment(&webapp)
+	createDeploy := resources.BuildDeployment(&webapp, resources.DeploymentOptions{
+		HardenedSecurityDefaults: r.HardenedSecurityDefaults,
+		Policies:                 policies,
+	})
 	if err := utils.SetOwnerRefence(&webapp, createDeploy, r.Scheme); err != nil {
 		return ctrl.Result{}, err
 	}
 	foundDeploy := &appsv1.Deployment{}
 	err = r.Get(ctx, types.NamespacedName{Namespace: webapp.Namespace, Name: webapp.Name}, foundDeploy)
 	if err != nil && errors.IsNotFound(err) {
+		resources.ApplyHibernation(&webapp, createDeploy, nil)
 		if err := r.Create(ctx, createDeploy); err != nil {
 			return ctrl.Result{}, err
 		}
 	} else if err != nil {
 		return ctrl.Result{}, err
+	} else if owned, conflict, err := r.claim(ctx, &webapp, foundDeploy); err != nil {
+		return ctrl.Result{}, err
+	} else if !owned {
+		conflicts = append(conflicts, conflict)
+		// do not report the replicas of someone else's Deployment
+		foundDeploy = &appsv1.Deployment{}
 	} else {
-		// compare config-hash
-		oldHash := foundDeploy.Spec.Template.Annotations[resources.WebAppHashKey]
-		newHash := createDeploy.Spec.Template.Annotations[resources.WebAppHashKey]
-		if oldHash != newHash {
-			foundDeploy.Spec.Template.Annotations = createDeploy.Spec.Template.Annotations
+		resources.ApplyHibernation(&webapp, createDeploy, foundDeploy)
+		oldReplicas, hibernated := foundDeploy.Annotations[resources.WebAppHibernatedReplicas]
+		newReplicas, hibernate := createDeploy.Annotations[resources.WebAppHibernatedReplicas]
+
+		// compare template-hash (includes config-hash, sidecars, volumes ...)
+		oldHash := foundDeploy.Spec.Template.Annotations[resources.WebAppTemplateHash]
+		newHash := createDeploy.Spec.Template.Annotations[resources.WebAppTemplateHash]
+		if resuming || oldHash != newHash || !reflect.DeepEqual(foundDeploy.Spec.Replicas, createDeploy.Spec.Replicas) ||
+			hibernated != hibernate || oldReplicas != newReplicas {
+			log.Info("Update Deployment", "TemplateHash", newHash, "Hibernate", hibernate)
+			foundDeploy.Spec.Replicas = createDeploy.Spec.Replicas
+			foundDeploy.Spec.Template = createDeploy.Spec.Template
+			if hibernate {
+				metav1.SetMetaDataAnnotation(&foundDeploy.ObjectMeta, resources.WebAppHibernatedReplicas, newReplicas)
+			} else {
+				delete(foundDeploy.Annotations, resources.WebAppHibernatedReplicas)
+			}
 			if err := r.Update(ctx, foundDeploy); err != nil {
 				return ctrl.Result{}, err
 			}
 		}
 	}
 
+	// Report pod security admission violations
+	if err := r.checkPodSecurity(ctx, &webapp, &createDeploy.Spec.Template); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Create service
 	createSvc := resources.BuildService(&webapp)
 	if err := utils.SetOwnerRefence(&webapp, createSvc, r.Scheme); err != nil {
 		return ctrl.Result{}, err
 	}
 	foundSvc := &corev1.Service{}
+	webapp.Status.ServiceEndpoints = nil
 	if err := r.Get(ctx, types.NamespacedName{Namespace: webapp.Namespace, Name: webapp.Name}, foundSvc); err != nil {
 		if errors.IsNotFound(err) {
 			if err := r.Create(ctx, createSvc); err != nil {
 				return ctrl.Result{}, err
 			}
+			webapp.Status.ServiceEndpoints = resources.ServiceEndpoints(createSvc)
+		}
+	} else if owned, conflict, err := r.claim(ctx, &webapp, foundSvc); err != nil {
+		return ctrl.Result{}, err
+	} else if !owned {
+		conflicts = append(conflicts, conflict)
+	} else {
+		webapp.Status.ServiceEndpoints = resources.ServiceEndpoints(foundSvc)
+	}
+
+	// Create or remove poddisruptionbudget
+	pdbConflicts, err := r.reconcilePodDisruptionBudget(ctx, &webapp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	conflicts = append(conflicts, pdbConflicts...)
+
+	// Create or remove networkpolicy
+	networkPolicyConflicts, err := r.reconcileNetworkPolicy(ctx, &webapp)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	conflicts = append(conflicts, networkPolicyConflicts...)
+
+	// Create httproute, or remove the one left over from a previous routing type
+	webapp.Status.URL = ""
+	webapp.Status.IngressAddresses = nil
+	if resources.UsesGateway(&webapp) {
+		routeConflicts, err := r.reconcileHTTPRoute(ctx, &webapp)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		conflicts = append(conflicts, routeConflicts...)
+		if err := r.deleteOwned(ctx, &webapp, &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{
+			Namespace: webapp.Namespace,
+			Name:      webapp.Name,
+		}}); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else {
+		meta.RemoveStatusCondition(&webapp.Status.Conditions, webappv1.ConditionRouteAccepted)
+		if r.GatewayAPIAvailable {
+			if err := r.deleteOwned(ctx, &webapp, &gatewayv1.HTTPRoute{ObjectMeta: metav1.ObjectMeta{
+				Namespace: webapp.Namespace,
+				Name:      webapp.Name,
+			}}); err != nil {
+				return ctrl.Result{}, err
+			}
 		}
 	}
 
 	// Create ingress
-	if webapp.Spec.Ingress != nil && webapp.Spec.Ingress.Enabled {
-		createIngress := resources.BuildIngress(&webapp)
+	if !resources.UsesGateway(&webapp) && webapp.Spec.Ingress != nil && webapp.Spec.Ingress.Enabled {
+		className, provider, err := r.resolveIngressProvider(ctx, &webapp)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		setIngressFeaturesCondition(&webapp, provider)
+
+		createIngress := resources.BuildIngress(&webapp, className, provider)
 		if err := utils.SetOwnerRefence(&webapp, createIngress, r.Scheme); err != nil {
 			return ctrl.Result{}, err
 		}
@@ -191,39 +380,568 @@ func (r *WebAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 					log.Error(err, "failed to create Ingress")
 					return ctrl.Result{}, err
 				}
+				webapp.Status.URL = resources.IngressURL(createIngress)
 			} else {
 				log.Error(err, "failed to get Ingress")
 				return ctrl.Result{}, err
 			}
+		} else if owned, conflict, err := r.claim(ctx, &webapp, &foundIngress); err != nil {
+			return ctrl.Result{}, err
+		} else if !owned {
+			conflicts = append(conflicts, conflict)
 		} else {
 			createIngress.ResourceVersion = foundIngress.ResourceVersion
+			createIngress.Annotations = resources.MergeIngressAnnotations(foundIngress.Annotations, createIngress.Annotations)
 			if err := r.Update(ctx, createIngress); err != nil {
 				log.Error(err, "failed to update Ingress")
 				return ctrl.Result{}, err
 			}
+			// the update response carries the load balancer status
+			webapp.Status.URL = resources.IngressURL(createIngress)
+			webapp.Status.IngressAddresses = resources.IngressAddresses(createIngress)
 		}
+	} else {
+		meta.RemoveStatusCondition(&webapp.Status.Conditions, webappv1.ConditionIngressFeatures)
 	}
 
+	setResourcesOwnedCondition(&webapp, conflicts)
+
 	// Get deployment status availableReplicas
-	availableReplicas := foundDeploy.Status.AvailableReplicas
-	if webapp.Status.AvailableReplicas != availableReplicas {
-		webapp.Status.AvailableReplicas = availableReplicas
+	webapp.Status.Replicas = ptr.Deref(createDeploy.Spec.Replicas, 1)
+	webapp.Status.AvailableReplicas = foundDeploy.Status.AvailableReplicas
+	setPhase(&webapp)
+	if !reflect.DeepEqual(oldStatus, &webapp.Status) {
 		if err := r.Status().Update(ctx, &webapp); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// wake up when the next schedule window opens or closes, or the image
+	// policy is due for a poll
+	requeueAfter := nextTransition
+	if nextPoll > 0 && (requeueAfter == 0 || nextPoll < requeueAfter) {
+		requeueAfter = nextPoll
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+func (r *WebAppReconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+	return r.Clock.Now()
+}
+
+// suspended reports whether the operator must leave the children alone.
+func suspended(webapp *webappv1.WebApp) bool {
+	return webapp.Spec.Suspend || webapp.Annotations[webappv1.PausedAnnotation] == "true"
+}
+
+// reconcileSuspended only refreshes the status of a suspended WebApp.
+func (r *WebAppReconciler) reconcileSuspended(ctx context.Context, webapp *webappv1.WebApp, oldStatus *webappv1.WebAppStatus) error {
+	foundDeploy := &appsv1.Deployment{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: webapp.Namespace, Name: webapp.Name}, foundDeploy)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	webapp.Status.Replicas = ptr.Deref(foundDeploy.Spec.Replicas, 0)
+	webapp.Status.AvailableReplicas = foundDeploy.Status.AvailableReplicas
+
+	condition := metav1.Condition{
+		Type:               webappv1.ConditionSuspended,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Suspended",
+		Message:            "spec.suspend is set, child resources are not updated",
+		ObservedGeneration: webapp.Generation,
+	}
+	if !webapp.Spec.Suspend {
+		condition.Reason = "Paused"
+		condition.Message = webappv1.PausedAnnotation + " annotation is set, child resources are not updated"
+	}
+	meta.SetStatusCondition(&webapp.Status.Conditions, condition)
+	setPhase(webapp)
+
+	if reflect.DeepEqual(oldStatus, &webapp.Status) {
+		return nil
+	}
+	return r.Status().Update(ctx, webapp)
+}
+
+// setPhase summarizes the WebApp state from spec and the desired and
+// available replicas, and sets the Ready condition accordingly.
+func setPhase(webapp *webappv1.WebApp) {
+	available := webapp.Status.AvailableReplicas >= webapp.Status.Replicas
+	switch {
+	case suspended(webapp):
+		webapp.Status.Phase = webappv1.WebAppPhaseSuspended
+	case webapp.Spec.Hibernate:
+		webapp.Status.Phase = webappv1.WebAppPhaseHibernated
+	case available:
+		webapp.Status.Phase = webappv1.WebAppPhaseRunning
+	default:
+		webapp.Status.Phase = webappv1.WebAppPhasePending
+	}
+
+	condition := metav1.Condition{
+		Type:               webappv1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ReplicasAvailable",
+		Message:            fmt.Sprintf("%d/%d replicas available", webapp.Status.AvailableReplicas, webapp.Status.Replicas),
+		ObservedGeneration: webapp.Generation,
+	}
+	if !available {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ReplicasUnavailable"
+	}
+	meta.SetStatusCondition(&webapp.Status.Conditions, condition)
+}
+
+// checkPodSecurity evaluates the pod template against the Pod Security
+// Admission level of the namespace and records the result as a condition,
+// so that violations show up on the WebApp instead of only in ReplicaSet events.
+func (r *WebAppReconciler) checkPodSecurity(ctx context.Context, webapp *webappv1.WebApp, template *corev1.PodTemplateSpec) error {
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: webapp.Namespace}, namespace); err != nil {
+		return err
+	}
+
+	condition := metav1.Condition{
+		Type:               webappv1.ConditionPodSecurity,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: webapp.Generation,
+	}
+	level, violations := resources.EvaluatePodSecurity(namespace, template)
+	if violations == "" {
+		condition.Reason = "Compliant"
+		condition.Message = "pod template satisfies the " + level + " pod security level"
+	} else {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Violation"
+		condition.Message = "pod template violates the " + level + " pod security level: " + violations
+	}
+	meta.SetStatusCondition(&webapp.Status.Conditions, condition)
+	return nil
+}
+
+// reconcileStorage creates a PersistentVolumeClaim for every spec.storage entry
+// and expands existing claims when the requested size grows. It returns the
+// claims that could not be adopted.
+func (r *WebAppReconciler) reconcileStorage(ctx context.Context, webapp *webappv1.WebApp) ([]string, error) {
+	log := logf.FromContext(ctx)
+	var conflicts []string
+	for _, storage := range webapp.Spec.Storage {
+		createPVC := resources.BuildPersistentVolumeClaim(webapp, storage)
+		if err := utils.SetOwnerRefence(webapp, createPVC, r.Scheme); err != nil {
+			return nil, err
+		}
+
+		foundPVC := &corev1.PersistentVolumeClaim{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: createPVC.Namespace, Name: createPVC.Name}, foundPVC)
+		if err != nil && errors.IsNotFound(err) {
+			if err := r.Create(ctx, createPVC); err != nil {
+				return nil, err
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		// claims retained by a previous WebApp with the same name are re-adopted
+		if owned, conflict, err := r.claim(ctx, webapp, foundPVC); err != nil {
+			return nil, err
+		} else if !owned {
+			conflicts = append(conflicts, conflict)
+			continue
+		}
+
+		// PVCs can only grow, shrinking is rejected by the API server
+		oldSize := foundPVC.Spec.Resources.Requests[corev1.ResourceStorage]
+		if storage.Size.Cmp(oldSize) > 0 {
+			log.Info("Expand PersistentVolumeClaim", "Name", foundPVC.Name, "From", oldSize.String(), "To", storage.Size.String())
+			foundPVC.Spec.Resources.Requests[corev1.ResourceStorage] = storage.Size
+			if err := r.Update(ctx, foundPVC); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// reconcilePodDisruptionBudget keeps the PodDisruptionBudget in sync with
+// spec.replicas and spec.podDisruptionBudget, deleting it when it is not wanted.
+// It returns the PodDisruptionBudget as a conflict when it could not be adopted.
+func (r *WebAppReconciler) reconcilePodDisruptionBudget(ctx context.Context, webapp *webappv1.WebApp) ([]string, error) {
+	log := logf.FromContext(ctx)
+	createPDB := resources.BuildPodDisruptionBudget(webapp)
+
+	foundPDB := &policyv1.PodDisruptionBudget{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: webapp.Namespace, Name: webapp.Name}, foundPDB)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	found := err == nil
+
+	if createPDB == nil {
+		if found && metav1.IsControlledBy(foundPDB, webapp) {
+			log.Info("Delete PodDisruptionBudget", "Name", foundPDB.Name)
+			if err := r.Delete(ctx, foundPDB); err != nil && !errors.IsNotFound(err) {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	if err := utils.SetOwnerRefence(webapp, createPDB, r.Scheme); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, r.Create(ctx, createPDB)
+	}
+	if owned, conflict, err := r.claim(ctx, webapp, foundPDB); err != nil {
+		return nil, err
+	} else if !owned {
+		return []string{conflict}, nil
+	}
+
+	if !reflect.DeepEqual(foundPDB.Spec.MinAvailable, createPDB.Spec.MinAvailable) ||
+		!reflect.DeepEqual(foundPDB.Spec.MaxUnavailable, createPDB.Spec.MaxUnavailable) {
+		log.Info("Update PodDisruptionBudget", "Name", foundPDB.Name)
+		foundPDB.Spec.MinAvailable = createPDB.Spec.MinAvailable
+		foundPDB.Spec.MaxUnavailable = createPDB.Spec.MaxUnavailable
+		return nil, r.Update(ctx, foundPDB)
+	}
+	return nil, nil
+}
+
+// resolveIngressProvider returns the IngressClass name to set on the Ingress and
+// the annotation provider matching the IngressClass controller. Without a class
+// name the cluster default IngressClass decides the provider.
+func (r *WebAppReconciler) resolveIngressProvider(ctx context.Context, webapp *webappv1.WebApp) (string, resources.IngressProvider, error) {
+	className := webapp.Spec.Ingress.ClassName
+	if className != "" {
+		ingressClass := &networkingv1.IngressClass{}
+		if err := r.Get(ctx, types.NamespacedName{Name: className}, ingressClass); err != nil {
+			if errors.IsNotFound(err) {
+				return className, resources.IngressProviderForClass(nil, className), nil
+			}
+			return "", nil, err
+		}
+		return className, resources.IngressProviderForClass(ingressClass, className), nil
+	}
+
+	var ingressClasses networkingv1.IngressClassList
+	if err := r.List(ctx, &ingressClasses); err != nil {
+		return "", nil, err
+	}
+	for i := range ingressClasses.Items {
+		if ingressClasses.Items[i].Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true" {
+			return "", resources.IngressProviderForClass(&ingressClasses.Items[i], ""), nil
+		}
+	}
+	return "", nil, nil
+}
+
+func setIngressFeaturesCondition(webapp *webappv1.WebApp, provider resources.IngressProvider) {
+	condition := metav1.Condition{
+		Type:               webappv1.ConditionIngressFeatures,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Supported",
+		ObservedGeneration: webapp.Generation,
+	}
+	if provider == nil {
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "UnknownIngressController"
+		condition.Message = "no annotation provider for the ingress controller, ingress features are not rendered"
+	} else if unsupported := provider.Unsupported(resources.BuildIngressFeatures(webapp)); len(unsupported) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Unsupported"
+		condition.Message = provider.Name() + " does not support: " + strings.Join(unsupported, ", ")
+	} else {
+		condition.Message = "all ingress features are rendered for " + provider.Name()
+	}
+	meta.SetStatusCondition(&webapp.Status.Conditions, condition)
+}
+
+// reconcileHTTPRoute creates or updates the HTTPRoute and mirrors its
+// acceptance by the parent Gateways into the RouteAccepted condition. It
+// returns the HTTPRoute as a conflict when it could not be adopted.
+func (r *WebAppReconciler) reconcileHTTPRoute(ctx context.Context, webapp *webappv1.WebApp) ([]string, error) {
+	log := logf.FromContext(ctx)
+	condition := metav1.Condition{
+		Type:               webappv1.ConditionRouteAccepted,
+		Status:             metav1.ConditionUnknown,
+		ObservedGeneration: webapp.Generation,
+	}
+	defer func() {
+		meta.SetStatusCondition(&webapp.Status.Conditions, condition)
+	}()
+
+	if !r.GatewayAPIAvailable {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "GatewayAPIUnavailable"
+		condition.Message = "the HTTPRoute CRD is not installed in the cluster"
+		return nil, nil
+	}
+	createRoute := resources.BuildHTTPRoute(webapp)
+	if createRoute == nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "MissingGateway"
+		condition.Message = "spec.routing.gateway is required when spec.routing.type is Gateway"
+		return nil, nil
+	}
+
+	if err := utils.SetOwnerRefence(webapp, createRoute, r.Scheme); err != nil {
+		return nil, err
+	}
+	webapp.Status.URL = resources.HTTPRouteURL(createRoute, webapp.Spec.Ingress != nil && webapp.Spec.Ingress.TLS)
+	foundRoute := &gatewayv1.HTTPRoute{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: createRoute.Namespace, Name: createRoute.Name}, foundRoute)
+	if err != nil && errors.IsNotFound(err) {
+		if err := r.Create(ctx, createRoute); err != nil {
+			return nil, err
+		}
+		condition.Reason = "Pending"
+		condition.Message = "waiting for the parent Gateways to accept the HTTPRoute"
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if owned, conflict, err := r.claim(ctx, webapp, foundRoute); err != nil {
+		return nil, err
+	} else if !owned {
+		webapp.Status.URL = ""
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "NotOwned"
+		condition.Message = conflict
+		return []string{conflict}, nil
+	}
+
+	if foundRoute.Annotations[resources.WebAppSpecHashKey] != createRoute.Annotations[resources.WebAppSpecHashKey] {
+		log.Info("Update HTTPRoute", "Name", foundRoute.Name)
+		createRoute.ResourceVersion = foundRoute.ResourceVersion
+		if err := r.Update(ctx, createRoute); err != nil {
+			return nil, err
+		}
+		condition.Reason = "Pending"
+		condition.Message = "waiting for the parent Gateways to accept the updated HTTPRoute"
+		return nil, nil
+	}
+
+	condition.Reason = "Pending"
+	condition.Message = "waiting for the parent Gateways to accept the HTTPRoute"
+	for _, parent := range foundRoute.Status.Parents {
+		accepted := meta.FindStatusCondition(parent.Conditions, string(gatewayv1.RouteConditionAccepted))
+		if accepted == nil {
+			continue
+		}
+		if accepted.Status != metav1.ConditionTrue {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = accepted.Reason
+			condition.Message = "parent " + string(parent.ParentRef.Name) + ": " + accepted.Message
+			return nil, nil
+		}
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Accepted"
+		condition.Message = "the HTTPRoute is accepted by its parent Gateways"
+	}
+	return nil, nil
+}
+
+// reconcileNetworkPolicy keeps the NetworkPolicy in sync with spec.networkPolicy.
+// It returns the NetworkPolicy as a conflict when it could not be adopted.
+func (r *WebAppReconciler) reconcileNetworkPolicy(ctx context.Context, webapp *webappv1.WebApp) ([]string, error) {
+	log := logf.FromContext(ctx)
+	createPolicy := resources.BuildNetworkPolicy(webapp)
+	if createPolicy == nil {
+		return nil, r.deleteOwned(ctx, webapp, &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+			Namespace: webapp.Namespace,
+			Name:      webapp.Name,
+		}})
+	}
+
+	if err := utils.SetOwnerRefence(webapp, createPolicy, r.Scheme); err != nil {
+		return nil, err
+	}
+	foundPolicy := &networkingv1.NetworkPolicy{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: createPolicy.Namespace, Name: createPolicy.Name}, foundPolicy)
+	if err != nil && errors.IsNotFound(err) {
+		return nil, r.Create(ctx, createPolicy)
+	} else if err != nil {
+		return nil, err
+	}
+	if owned, conflict, err := r.claim(ctx, webapp, foundPolicy); err != nil {
+		return nil, err
+	} else if !owned {
+		return []string{conflict}, nil
+	}
+
+	if !reflect.DeepEqual(foundPolicy.Spec, createPolicy.Spec) {
+		log.Info("Update NetworkPolicy", "Name", foundPolicy.Name)
+		foundPolicy.Spec = createPolicy.Spec
+		return nil, r.Update(ctx, foundPolicy)
+	}
+	return nil, nil
+}
+
+// reconcileServiceAccount creates the ServiceAccount, Role and RoleBinding
+// requested by spec.serviceAccount and removes the ones no longer requested.
+// It returns the objects that could not be adopted. The Role and RoleBinding
+// are left alone unless the WebApp controls every object they depend on, so
+// that rules are never granted through someone else's Role or ServiceAccount.
+func (r *WebAppReconciler) reconcileServiceAccount(ctx context.Context, webapp *webappv1.WebApp) ([]string, error) {
+	log := logf.FromContext(ctx)
+	var conflicts []string
+
+	// serviceaccount
+	if createSA := resources.BuildServiceAccount(webapp); createSA != nil {
+		if err := utils.SetOwnerRefence(webapp, createSA, r.Scheme); err != nil {
+			return nil, err
+		}
+		foundSA := &corev1.ServiceAccount{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: createSA.Namespace, Name: createSA.Name}, foundSA)
+		if err != nil && errors.IsNotFound(err) {
+			if err := r.Create(ctx, createSA); err != nil {
+				return nil, err
+			}
+		} else if err != nil {
+			return nil, err
+		} else if owned, conflict, err := r.claim(ctx, webapp, foundSA); err != nil {
+			return nil, err
+		} else if !owned {
+			conflicts = append(conflicts, conflict)
+		} else if !reflect.DeepEqual(foundSA.Annotations, createSA.Annotations) ||
+			!reflect.DeepEqual(foundSA.AutomountServiceAccountToken, createSA.AutomountServiceAccountToken) {
+			log.Info("Update ServiceAccount", "Name", foundSA.Name)
+			foundSA.Annotations = createSA.Annotations
+			foundSA.AutomountServiceAccountToken = createSA.AutomountServiceAccountToken
+			if err := r.Update(ctx, foundSA); err != nil {
+				return nil, err
+			}
+		}
+	} else if err := r.deleteOwned(ctx, webapp, &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{
+		Namespace: webapp.Namespace,
+		Name:      webapp.Name,
+	}}); err != nil {
+		return nil, err
+	}
+
+	createRole := resources.BuildRole(webapp)
+	createRoleBinding := resources.BuildRoleBinding(webapp)
+	if createRole == nil {
+		if err := r.deleteOwned(ctx, webapp, &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{
+			Namespace: webapp.Namespace,
+			Name:      webapp.Name,
+		}}); err != nil {
+			return nil, err
+		}
+		return conflicts, r.deleteOwned(ctx, webapp, &rbacv1.Role{ObjectMeta: metav1.ObjectMeta{
+			Namespace: webapp.Namespace,
+			Name:      webapp.Name,
+		}})
+	}
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+
+	// role
+	if err := utils.SetOwnerRefence(webapp, createRole, r.Scheme); err != nil {
+		return nil, err
+	}
+	foundRole := &rbacv1.Role{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: createRole.Namespace, Name: createRole.Name}, foundRole)
+	if err != nil && errors.IsNotFound(err) {
+		if err := r.Create(ctx, createRole); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	} else if owned, conflict, err := r.claim(ctx, webapp, foundRole); err != nil {
+		return nil, err
+	} else if !owned {
+		return append(conflicts, conflict), nil
+	} else if !reflect.DeepEqual(foundRole.Rules, createRole.Rules) {
+		log.Info("Update Role", "Name", foundRole.Name)
+		foundRole.Rules = createRole.Rules
+		if err := r.Update(ctx, foundRole); err != nil {
+			return nil, err
+		}
+	}
+
+	// rolebinding
+	if err := utils.SetOwnerRefence(webapp, createRoleBinding, r.Scheme); err != nil {
+		return nil, err
+	}
+	foundRoleBinding := &rbacv1.RoleBinding{}
+	err = r.Get(ctx, types.NamespacedName{Namespace: createRoleBinding.Namespace, Name: createRoleBinding.Name}, foundRoleBinding)
+	if err != nil && errors.IsNotFound(err) {
+		return conflicts, r.Create(ctx, createRoleBinding)
+	} else if err != nil {
+		return nil, err
+	}
+	if foundRoleBinding.RoleRef != createRoleBinding.RoleRef {
+		// roleRef is immutable, a RoleBinding to another role is not adopted
+		return append(conflicts, fmt.Sprintf("RoleBinding/%s references %s/%s", foundRoleBinding.Name,
+			foundRoleBinding.RoleRef.Kind, foundRoleBinding.RoleRef.Name)), nil
+	}
+	if owned, conflict, err := r.claim(ctx, webapp, foundRoleBinding); err != nil {
+		return nil, err
+	} else if !owned {
+		return append(conflicts, conflict), nil
+	}
+	if !reflect.DeepEqual(foundRoleBinding.Subjects, createRoleBinding.Subjects) {
+		log.Info("Update RoleBinding", "Name", foundRoleBinding.Name)
+		foundRoleBinding.Subjects = createRoleBinding.Subjects
+		return conflicts, r.Update(ctx, foundRoleBinding)
+	}
+	return conflicts, nil
+}
+
+// deleteOwned deletes obj if it exists and is controlled by the WebApp, so that
+// objects the WebApp merely references are never removed.
+func (r *WebAppReconciler) deleteOwned(ctx context.Context, webapp *webappv1.WebApp, obj client.Object) error {
+	if err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(obj, webapp) {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, obj))
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *WebAppReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	// only watch HTTPRoutes when the Gateway API CRDs are installed,
+	// otherwise the controller would fail to start
+	_, err := mgr.GetRESTMapper().RESTMapping(gatewayv1.SchemeGroupVersion.WithKind("HTTPRoute").GroupKind(), gatewayv1.SchemeGroupVersion.Version)
+	if err != nil && !meta.IsNoMatchError(err) {
+		return err
+	}
+	r.GatewayAPIAvailable = err == nil
+
+	builder := ctrl.NewControllerManagedBy(mgr)
+	if r.GatewayAPIAvailable {
+		builder = builder.Owns(&gatewayv1.HTTPRoute{})
+	}
+	if r.Shards != nil {
+		builder = builder.WatchesRawSource(r.Shards.Source())
+	}
+	builder = builder.Watches(&webappv1.WebAppPolicy{}, handler.EnqueueRequestsFromMapFunc(r.webAppsForPolicy))
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &webappv1.WebApp{}, templateRefIndex, indexTemplateRef); err != nil {
+		return err
+	}
+	builder = builder.Watches(&webappv1.WebAppTemplate{}, handler.EnqueueRequestsFromMapFunc(r.webAppsForTemplate))
+	return builder.
 		For(&webappv1.WebApp{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.Service{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&networkingv1.Ingress{}).
+		Owns(&corev1.PersistentVolumeClaim{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
 		Named("webapp").
 		Complete(r)
 }
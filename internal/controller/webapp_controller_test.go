@@ -18,16 +18,35 @@ package controller
 
 import (
 	"context"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/registry"
+	"github.com/hoon77/crd-operator/internal/pkg/registry/registrytest"
+	"github.com/hoon77/crd-operator/internal/pkg/resources"
+	"github.com/hoon77/crd-operator/internal/pkg/sharding"
 )
 
 var _ = Describe("WebApp Controller", func() {
@@ -51,34 +70,1086 @@ var _ = Describe("WebApp Controller", func() {
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: webappv1.WebAppSpec{
+						Image:    "nginx:latest",
+						Replicas: ptr.To[int32](1),
+						InitContainers: []corev1.Container{
+							{Name: "migrate", Image: "busybox"},
+						},
+						Sidecars: []corev1.Container{
+							{Name: "log-shipper", Image: "fluent-bit"},
+							{Name: "proxy", Image: "envoy", RestartPolicy: ptr.To(corev1.ContainerRestartPolicyAlways)},
+						},
+						Volumes: []corev1.Volume{
+							{Name: "logs", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: "logs", MountPath: "/var/log/nginx"},
+						},
+						Storage: []webappv1.StorageSpec{
+							{Name: "uploads", MountPath: "/uploads", Size: resource.MustParse("1Gi")},
+						},
+						Scheduling: &webappv1.SchedulingSpec{
+							SpreadAcrossZones: true,
+						},
+						ServiceAccount: &webappv1.ServiceAccountSpec{
+							Create:                       true,
+							AutomountServiceAccountToken: ptr.To(false),
+							Rules: []rbacv1.PolicyRule{
+								{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}},
+							},
+						},
+						NetworkPolicy: &webappv1.NetworkPolicySpec{
+							Enabled:     true,
+							DefaultDeny: true,
+							Egress: []webappv1.NetworkPolicyEgressRule{
+								{CIDRs: []string{"10.0.0.0/8"}},
+							},
+						},
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
 		})
 
 		AfterEach(func() {
-			// TODO(user): Cleanup logic after each test, like removing the resource instance.
 			resource := &webappv1.WebApp{}
 			err := k8sClient.Get(ctx, typeNamespacedName, resource)
 			Expect(err).NotTo(HaveOccurred())
 
 			By("Cleanup the specific resource instance WebApp")
 			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			By("Reconciling the deletion to release the finalizer")
+			finalizeWebApp(ctx, typeNamespacedName)
 		})
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
-			controllerReconciler := &WebAppReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+			reconcileWebApp(ctx, typeNamespacedName)
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &corev1.Service{})).To(Succeed())
+		})
+
+		It("should merge init containers and sidecars into the pod template", func() {
+			reconcileWebApp(ctx, typeNamespacedName)
+
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			podSpec := deploy.Spec.Template.Spec
+			Expect(podSpec.InitContainers).To(HaveLen(2))
+			Expect(podSpec.InitContainers[0].Name).To(Equal("proxy"))
+			Expect(podSpec.InitContainers[1].Name).To(Equal("migrate"))
+			Expect(podSpec.Containers).To(HaveLen(2))
+			Expect(podSpec.Containers[0].VolumeMounts).To(HaveLen(2))
+			Expect(podSpec.Containers[1].Name).To(Equal("log-shipper"))
+			Expect(podSpec.Volumes).To(HaveLen(2))
+		})
+
+		It("should create and mount the persistentvolumeclaims", func() {
+			reconcileWebApp(ctx, typeNamespacedName)
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: resourceName + "-uploads"}, pvc)).To(Succeed())
+			Expect(pvc.OwnerReferences).To(HaveLen(1))
+			Expect(pvc.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(deploy.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(HaveField("MountPath", "/uploads")))
+		})
+
+		It("should generate zone and host constraints from the spreadAcrossZones preset", func() {
+			reconcileWebApp(ctx, typeNamespacedName)
+
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			constraints := deploy.Spec.Template.Spec.TopologySpreadConstraints
+			Expect(constraints).To(HaveLen(2))
+			Expect(constraints[0].TopologyKey).To(Equal(resources.ZoneTopologyKey))
+			Expect(constraints[1].TopologyKey).To(Equal(resources.HostnameTopologyKey))
+		})
+
+		It("should create the serviceaccount and its rbac", func() {
+			reconcileWebApp(ctx, typeNamespacedName)
+
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(deploy.Spec.Template.Spec.ServiceAccountName).To(Equal(resourceName))
+			Expect(deploy.Spec.Template.Spec.AutomountServiceAccountToken).To(Equal(ptr.To(false)))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &corev1.ServiceAccount{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &rbacv1.Role{})).To(Succeed())
+			roleBinding := &rbacv1.RoleBinding{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, roleBinding)).To(Succeed())
+			Expect(roleBinding.Subjects[0].Name).To(Equal(resourceName))
+		})
+
+		It("should create a networkpolicy allowing dns and the declared egress", func() {
+			reconcileWebApp(ctx, typeNamespacedName)
+
+			networkPolicy := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, networkPolicy)).To(Succeed())
+			Expect(networkPolicy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress))
+			Expect(networkPolicy.Spec.Egress).To(HaveLen(2))
+		})
+
+		It("should manage the PodDisruptionBudget from spec.replicas", func() {
+			controllerReconciler := newReconciler()
+			reconcileTwice := func() {
+				for range 2 {
+					_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+					Expect(err).NotTo(HaveOccurred())
+				}
+			}
+			reconcileTwice()
+
+			By("Checking a single replica has no PodDisruptionBudget")
+			pdb := &policyv1.PodDisruptionBudget{}
+			err := k8sClient.Get(ctx, typeNamespacedName, pdb)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			By("Scaling out to 3 replicas")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			webapp.Spec.Replicas = ptr.To[int32](3)
+			Expect(k8sClient.Update(ctx, webapp)).To(Succeed())
+			reconcileTwice()
+			Expect(k8sClient.Get(ctx, typeNamespacedName, pdb)).To(Succeed())
+			Expect(pdb.Spec.MaxUnavailable).To(Equal(ptr.To(intstr.FromInt32(1))))
+
+			By("Scaling back to 1 replica")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			webapp.Spec.Replicas = ptr.To[int32](1)
+			Expect(k8sClient.Update(ctx, webapp)).To(Succeed())
+			reconcileTwice()
+			err = k8sClient.Get(ctx, typeNamespacedName, pdb)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("When the WebApp is routed through a Gateway", func() {
+		ctx := context.Background()
+		typeNamespacedName := types.NamespacedName{Name: "gateway-webapp", Namespace: "default"}
+
+		AfterEach(func() {
+			deleteWebApp(ctx, typeNamespacedName)
+		})
+
+		It("should create an HTTPRoute derived from spec.ingress", func() {
+			webapp := &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec: webappv1.WebAppSpec{
+					Image:    "nginx:latest",
+					Replicas: ptr.To[int32](1),
+					Ingress: &webappv1.IngressSpec{
+						Host:          "app.example.com",
+						Path:          "/main",
+						RewriteTarget: "/",
+					},
+					Routing: &webappv1.RoutingSpec{
+						Type: webappv1.RoutingTypeGateway,
+						Gateway: &webappv1.GatewayRouteSpec{
+							ParentRefs: []webappv1.GatewayParentReference{{Name: "public", Namespace: "gateways"}},
+						},
+					},
+				},
+			}
+			createWebApp(ctx, newReconciler(), webapp)
+
+			route := &gatewayv1.HTTPRoute{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, route)).To(Succeed())
+			Expect(route.Spec.Hostnames).To(ConsistOf(gatewayv1.Hostname("app.example.com")))
+			Expect(route.Spec.Rules).To(HaveLen(1))
+			Expect(*route.Spec.Rules[0].Matches[0].Path.Value).To(Equal("/main"))
+			Expect(route.Spec.Rules[0].Filters[0].URLRewrite.Path.ReplacePrefixMatch).To(Equal(ptr.To("/")))
+
+			condition := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionRouteAccepted)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+
+			By("Checking the URL and service endpoints are published")
+			Expect(webapp.Status.URL).To(Equal("http://app.example.com/main"))
+			Expect(webapp.Status.ServiceEndpoints).To(ContainElement(HaveField("Type", corev1.ServiceTypeNodePort)))
+			Expect(webapp.Status.IngressAddresses).To(BeEmpty())
+		})
+	})
+
+	Context("When the namespace enforces the restricted pod security level", func() {
+		const namespaceName = "restricted"
+		ctx := context.Background()
+		typeNamespacedName := types.NamespacedName{Name: "secure-webapp", Namespace: namespaceName}
+
+		AfterEach(func() {
+			deleteWebApp(ctx, typeNamespacedName)
+		})
+
+		It("should report violations unless hardened defaults are applied", func() {
+			By("creating a restricted namespace and a WebApp in it")
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   namespaceName,
+				Labels: map[string]string{"pod-security.kubernetes.io/enforce": "restricted"},
+			}})).To(Succeed())
+			webapp := &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: namespaceName},
+				Spec:       webappv1.WebAppSpec{Image: "nginx:latest", Replicas: ptr.To[int32](1)},
+			}
+			createWebApp(ctx, newReconciler(), webapp)
+
+			condition := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionPodSecurity)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+
+			hardenedReconciler := newReconciler()
+			hardenedReconciler.HardenedSecurityDefaults = true
+			reconcileAndGet(ctx, hardenedReconciler, webapp)
+			Expect(meta.IsStatusConditionTrue(webapp.Status.Conditions, webappv1.ConditionPodSecurity)).To(BeTrue())
+		})
+	})
+
+	Context("When children with the WebApp's names already exist", func() {
+		ctx := context.Background()
+		typeNamespacedName := types.NamespacedName{Name: "adopt-webapp", Namespace: "default"}
+		foreignRoleName := types.NamespacedName{Name: "foreign-role-webapp", Namespace: "default"}
+		foreignPDBName := types.NamespacedName{Name: "foreign-pdb-webapp", Namespace: "default"}
+
+		AfterEach(func() {
+			deleteWebApp(ctx, typeNamespacedName)
+			deleteWebApp(ctx, foreignRoleName)
+			deleteWebApp(ctx, foreignPDBName)
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: foreignPDBName.Name, Namespace: foreignPDBName.Namespace},
+			}))).To(Succeed())
+		})
+
+		It("should adopt them according to the adoption policy", func() {
+			By("creating an unowned Service and a Deployment controlled by another object")
+			Expect(k8sClient.Create(ctx, &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}},
+			})).To(Succeed())
+			other := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other-owner", Namespace: typeNamespacedName.Namespace}}
+			Expect(k8sClient.Create(ctx, other)).To(Succeed())
+			deploy := resources.BuildDeployment(&webappv1.WebApp{ObjectMeta: metav1.ObjectMeta{
+				Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace,
+			}}, resources.DeploymentOptions{})
+			deploy.Spec.Template.Spec.Containers[0].Image = "nginx:latest"
+			Expect(controllerutil.SetControllerReference(other, deploy, k8sClient.Scheme())).To(Succeed())
+			Expect(k8sClient.Create(ctx, deploy)).To(Succeed())
+
+			webapp := &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec:       webappv1.WebAppSpec{Image: "nginx:latest", Replicas: ptr.To[int32](1)},
+			}
+			createWebApp(ctx, newReconciler(), webapp)
+
+			By("refusing both with the default Never policy")
+			condition := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionResourcesOwned)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("Service/adopt-webapp exists and is not owned"))
+			Expect(condition.Message).To(ContainSubstring("Deployment/adopt-webapp is controlled by ConfigMap/other-owner"))
+
+			By("adopting only the unowned Service with IfUnowned")
+			webapp.Spec.AdoptionPolicy = webappv1.AdoptionPolicyIfUnowned
+			Expect(k8sClient.Update(ctx, webapp)).To(Succeed())
+			reconcileAndGet(ctx, newReconciler(), webapp)
+			condition = meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionResourcesOwned)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).NotTo(ContainSubstring("Service"))
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, service)).To(Succeed())
+			Expect(metav1.IsControlledBy(service, webapp)).To(BeTrue())
+
+			By("taking over the Deployment with Force")
+			webapp.Spec.AdoptionPolicy = webappv1.AdoptionPolicyForce
+			Expect(k8sClient.Update(ctx, webapp)).To(Succeed())
+			reconcileAndGet(ctx, newReconciler(), webapp)
+			condition = meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionResourcesOwned)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(metav1.IsControlledBy(deploy, webapp)).To(BeTrue())
+			Expect(deploy.OwnerReferences).To(HaveLen(1))
+		})
+
+		It("should not grant rules through a Role it does not control", func() {
+			foreignRules := []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list"}}}
+			Expect(k8sClient.Create(ctx, &rbacv1.Role{
+				ObjectMeta: metav1.ObjectMeta{Name: foreignRoleName.Name, Namespace: foreignRoleName.Namespace},
+				Rules:      foreignRules,
+			})).To(Succeed())
+			webapp := &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: foreignRoleName.Name, Namespace: foreignRoleName.Namespace},
+				Spec: webappv1.WebAppSpec{
+					Image:    "nginx:latest",
+					Replicas: ptr.To[int32](1),
+					ServiceAccount: &webappv1.ServiceAccountSpec{
+						Create: true,
+						Rules:  []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}},
+					},
+				},
+			}
+			createWebApp(ctx, newReconciler(), webapp)
+
+			condition := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionResourcesOwned)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("Role/foreign-role-webapp exists and is not owned"))
+			role := &rbacv1.Role{}
+			Expect(k8sClient.Get(ctx, foreignRoleName, role)).To(Succeed())
+			Expect(role.Rules).To(Equal(foreignRules))
+			err := k8sClient.Get(ctx, foreignRoleName, &rbacv1.RoleBinding{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should leave a PodDisruptionBudget it does not control alone", func() {
+			Expect(k8sClient.Create(ctx, &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: foreignPDBName.Name, Namespace: foreignPDBName.Namespace},
+				Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromInt32(2))},
+			})).To(Succeed())
+			webapp := &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: foreignPDBName.Name, Namespace: foreignPDBName.Namespace},
+				Spec:       webappv1.WebAppSpec{Image: "nginx:latest", Replicas: ptr.To[int32](3)},
+			}
+			createWebApp(ctx, newReconciler(), webapp)
+
+			condition := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionResourcesOwned)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("PodDisruptionBudget/foreign-pdb-webapp exists and is not owned"))
+			pdb := &policyv1.PodDisruptionBudget{}
+			Expect(k8sClient.Get(ctx, foreignPDBName, pdb)).To(Succeed())
+			Expect(pdb.Spec.MinAvailable).To(Equal(ptr.To(intstr.FromInt32(2))))
+			Expect(pdb.Spec.MaxUnavailable).To(BeNil())
+			Expect(pdb.OwnerReferences).To(BeEmpty())
+		})
+	})
+
+	Context("When the WebApp is suspended or hibernated", func() {
+		ctx := context.Background()
+		typeNamespacedName := types.NamespacedName{Name: "suspend-webapp", Namespace: "default"}
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec:       webappv1.WebAppSpec{Image: "nginx:latest", Replicas: ptr.To[int32](2)},
+			})).To(Succeed())
+			reconcileWebApp(ctx, typeNamespacedName)
+		})
+
+		AfterEach(func() {
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, webapp)).To(Succeed())
+			finalizeWebApp(ctx, typeNamespacedName)
+		})
+
+		updateWebApp := func(mutate func(*webappv1.WebApp)) {
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			mutate(webapp)
+			Expect(k8sClient.Update(ctx, webapp)).To(Succeed())
+			reconcileWebApp(ctx, typeNamespacedName)
+		}
+
+		It("should keep hand edits while paused and still update status", func() {
+			updateWebApp(func(webapp *webappv1.WebApp) {
+				webapp.Annotations = map[string]string{webappv1.PausedAnnotation: "true"}
+			})
+
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			deploy.Spec.Template.Spec.Containers[0].Image = "nginx:hotfix"
+			Expect(k8sClient.Update(ctx, deploy)).To(Succeed())
+			reconcileWebApp(ctx, typeNamespacedName)
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:hotfix"))
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(webapp.Status.Phase).To(Equal(webappv1.WebAppPhaseSuspended))
+			Expect(meta.IsStatusConditionTrue(webapp.Status.Conditions, webappv1.ConditionSuspended)).To(BeTrue())
+
+			By("reverting the edit on resume")
+			updateWebApp(func(webapp *webappv1.WebApp) {
+				delete(webapp.Annotations, webappv1.PausedAnnotation)
+			})
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:latest"))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionSuspended)).To(BeNil())
+		})
+
+		It("should scale to zero and restore the replica count", func() {
+			updateWebApp(func(webapp *webappv1.WebApp) { webapp.Spec.Hibernate = true })
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(*deploy.Spec.Replicas).To(BeZero())
+			Expect(deploy.Annotations).To(HaveKeyWithValue(resources.WebAppHibernatedReplicas, "2"))
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(webapp.Status.Phase).To(Equal(webappv1.WebAppPhaseHibernated))
+			Expect(webapp.Status.Replicas).To(BeZero())
+			Expect(meta.IsStatusConditionTrue(webapp.Status.Conditions, webappv1.ConditionReady)).To(BeTrue())
+
+			updateWebApp(func(webapp *webappv1.WebApp) { webapp.Spec.Hibernate = false })
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(*deploy.Spec.Replicas).To(Equal(int32(2)))
+			Expect(deploy.Annotations).NotTo(HaveKey(resources.WebAppHibernatedReplicas))
+			// envtest runs no pods, so the restored replicas never become available
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(webapp.Status.Replicas).To(Equal(int32(2)))
+			Expect(meta.IsStatusConditionFalse(webapp.Status.Conditions, webappv1.ConditionReady)).To(BeTrue())
+		})
+	})
+
+	Context("When the WebApp has schedules", func() {
+		ctx := context.Background()
+		typeNamespacedName := types.NamespacedName{Name: "scheduled-webapp", Namespace: "default"}
+
+		AfterEach(func() {
+			deleteWebApp(ctx, typeNamespacedName)
+		})
+
+		It("should apply the active window and requeue at the next transition", func() {
+			webapp := &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec: webappv1.WebAppSpec{
+					Image:    "nginx:latest",
+					Replicas: ptr.To[int32](3),
+					Schedules: []webappv1.ScheduleSpec{
+						{Name: "night", Start: "0 20 * * *", End: "0 8 * * *", Replicas: ptr.To[int32](1)},
+					},
+				},
+			}
+			reconciler := newReconciler()
+			reconciler.Clock = testingclock.NewFakePassiveClock(time.Date(2026, 10, 14, 23, 0, 0, 0, time.UTC))
+			result := createWebApp(ctx, reconciler, webapp)
+			Expect(result.RequeueAfter).To(Equal(9 * time.Hour))
+
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(*deploy.Spec.Replicas).To(Equal(int32(1)))
+			Expect(webapp.Status.ActiveSchedule).To(Equal("night"))
+		})
+	})
+
+	Context("When sharding is enabled", func() {
+		typeNamespacedName := types.NamespacedName{Name: "sharded-webapp", Namespace: "default"}
+
+		It("should label the WebApps of its shard and release its Lease on stop", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			shards := sharding.NewCoordinator(k8sClient, k8sClient, "default", "operator-a")
+			stopped := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(stopped)
+				Expect(shards.Start(ctx)).To(Succeed())
+			}()
+			// a single member owns every WebApp
+			Eventually(func() bool { return shards.Owns(typeNamespacedName) }).Should(BeTrue())
+
+			Expect(k8sClient.Create(ctx, &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec:       webappv1.WebAppSpec{Image: "nginx:latest", Replicas: ptr.To[int32](1)},
+			})).To(Succeed())
+			reconciler := newReconciler()
+			reconciler.Shards = shards
+			for range 2 {
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(webapp.Labels).To(HaveKeyWithValue(sharding.ShardLabel, "operator-a"))
+
+			lease := &coordinationv1.Lease{}
+			leaseKey := types.NamespacedName{Namespace: "default", Name: "webapp-shard-operator-a"}
+			Expect(k8sClient.Get(ctx, leaseKey, lease)).To(Succeed())
+			Expect(lease.Labels).To(HaveKey(sharding.MemberLabel))
+
+			cancel()
+			Eventually(stopped).Should(BeClosed())
+			err := k8sClient.Get(context.Background(), leaseKey, lease)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			Expect(k8sClient.Delete(context.Background(), webapp)).To(Succeed())
+			finalizeWebApp(context.Background(), typeNamespacedName)
+		})
+	})
+
+	Context("When a WebAppPolicy selects the namespace", func() {
+		ctx := context.Background()
+		typeNamespacedName := types.NamespacedName{Name: "policy-webapp", Namespace: "policy-test"}
+		objectMeta := metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace}
+
+		AfterEach(func() {
+			deleteWebApp(ctx, typeNamespacedName)
+			// envtest runs no namespace controller, so the children are deleted one by one
+			deleteObjects(ctx,
+				&appsv1.Deployment{ObjectMeta: objectMeta},
+				&corev1.Service{ObjectMeta: objectMeta},
+				&webappv1.WebAppPolicy{ObjectMeta: metav1.ObjectMeta{Name: "payments"}},
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Namespace}},
+			)
+		})
+
+		It("should hold back violating WebApps and apply the policy defaults", func() {
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   typeNamespacedName.Namespace,
+				Labels: map[string]string{"team": "payments"},
+			}})).To(Succeed())
+			policy := &webappv1.WebAppPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+				Spec: webappv1.WebAppPolicySpec{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+					Limits:            webappv1.WebAppPolicyLimits{MaxReplicas: ptr.To[int32](2)},
+					Defaults:          webappv1.WebAppPolicyDefaults{Labels: map[string]string{"team": "payments"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, policy)).To(Succeed())
+
+			Expect(k8sClient.Create(ctx, &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec:       webappv1.WebAppSpec{Image: "nginx:latest", Replicas: ptr.To[int32](3)},
+			})).To(Succeed())
+			reconcileWebApp(ctx, typeNamespacedName)
+
+			By("Checking the violation is reported and no Deployment is created")
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			condition := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionPolicyCompliant)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("replicas 3 exceed the maximum of 2"))
+			err := k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			By("Complying with the policy")
+			webapp.Spec.Replicas = ptr.To[int32](2)
+			Expect(k8sClient.Update(ctx, webapp)).To(Succeed())
+			reconcileWebApp(ctx, typeNamespacedName)
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(webapp.Status.Conditions, webappv1.ConditionPolicyCompliant)).To(BeTrue())
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(deploy.Spec.Template.Labels).To(HaveKeyWithValue("team", "payments"))
+			Expect(deploy.Spec.Selector.MatchLabels).NotTo(HaveKey("team"))
+
+			Expect(k8sClient.Delete(ctx, webapp)).To(Succeed())
+			finalizeWebApp(ctx, typeNamespacedName)
+		})
+	})
+
+	Context("When image digests are resolved", func() {
+		ctx := context.Background()
+		typeNamespacedName := types.NamespacedName{Name: "pinned-webapp", Namespace: "default"}
+
+		It("should pin the image until it changes and reject other registries", func() {
+			server := registrytest.NewServer()
+			defer server.Close()
+			image := server.Host() + "/team/web:1.0"
+			digest := server.Push("team/web", "1.0", "web 1.0")
+
+			reconciler := newReconciler()
+			reconciler.ImageResolver = &registry.Client{HTTPClient: server.Client()}
+			reconciler.AllowedRegistries = []string{server.Host()}
+			reconcilePinned := func() {
+				for range 2 {
+					_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+					Expect(err).NotTo(HaveOccurred())
+				}
+			}
+
+			Expect(k8sClient.Create(ctx, &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec:       webappv1.WebAppSpec{Image: image, Replicas: ptr.To[int32](1)},
+			})).To(Succeed())
+			reconcilePinned()
+
+			By("Checking the Deployment runs the digest")
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(webapp.Spec.Image).To(Equal(image))
+			Expect(webapp.Status.ResolvedImage).To(Equal(image + "@" + digest))
+			Expect(meta.IsStatusConditionTrue(webapp.Status.Conditions, webappv1.ConditionImageResolved)).To(BeTrue())
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal(image + "@" + digest))
+
+			By("Keeping the digest when the tag moves")
+			server.Push("team/web", "1.0", "web 1.0 rebuilt")
+			reconcilePinned()
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal(image + "@" + digest))
+
+			By("Rejecting a sidecar from another registry")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			webapp.Spec.Sidecars = []corev1.Container{{Name: "proxy", Image: "envoyproxy/envoy:v1.31"}}
+			Expect(k8sClient.Update(ctx, webapp)).To(Succeed())
+			reconcilePinned()
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			condition := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionImageResolved)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal("RegistryNotAllowed"))
+			Expect(condition.Message).To(ContainSubstring("envoyproxy/envoy:v1.31"))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			Expect(deploy.Spec.Template.Spec.Containers).To(HaveLen(1))
+
+			Expect(k8sClient.Delete(ctx, webapp)).To(Succeed())
+			finalizeWebApp(ctx, typeNamespacedName)
+		})
+	})
+
+	Context("When the WebApp has an image policy", func() {
+		ctx := context.Background()
+		typeNamespacedName := types.NamespacedName{Name: "image-policy-webapp", Namespace: "default"}
+
+		It("should follow the newest matching tag and keep a rollback until a newer one", func() {
+			server := registrytest.NewServer()
+			defer server.Close()
+			for _, tag := range []string{"1.0", "1.1", "2.0", "latest"} {
+				server.Push("team/web", tag, "web "+tag)
+			}
+			image := server.Host() + "/team/web"
+
+			clock := testingclock.NewFakePassiveClock(time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC))
+			reconciler := newReconciler()
+			reconciler.TagLister = &registry.Client{HTTPClient: server.Client()}
+			reconciler.Clock = clock
+			var result reconcile.Result
+			reconcilePolicy := func() {
+				for range 3 {
+					var err error
+					result, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+					Expect(err).NotTo(HaveOccurred())
+				}
+			}
+			expectImages := func(specImage, deployImage string) {
+				webapp := &webappv1.WebApp{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+				Expect(webapp.Spec.Image).To(Equal(specImage))
+				deploy := &appsv1.Deployment{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+				Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal(deployImage))
 			}
 
-			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
-				NamespacedName: typeNamespacedName,
+			Expect(k8sClient.Create(ctx, &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec: webappv1.WebAppSpec{
+					Image:    image + ":1.0",
+					Replicas: ptr.To[int32](1),
+					ImagePolicy: &webappv1.ImagePolicySpec{
+						Semver:   ">=1.0.0 <2.0.0",
+						Interval: &metav1.Duration{Duration: 10 * time.Minute},
+					},
+				},
+			})).To(Succeed())
+			reconcilePolicy()
+
+			By("Checking the newest tag in range is rolled out")
+			expectImages(image+":1.1", image+":1.1")
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(webapp.Status.ImagePolicy).NotTo(BeNil())
+			Expect(webapp.Status.ImagePolicy.PreviousImage).To(Equal(image + ":1.0"))
+			Expect(webapp.Status.ImagePolicy.LatestImage).To(Equal(image + ":1.1"))
+			Expect(meta.IsStatusConditionTrue(webapp.Status.Conditions, webappv1.ConditionImageUpToDate)).To(BeTrue())
+			Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+
+			By("Rolling back to the previous image")
+			webapp.Spec.Image = webapp.Status.ImagePolicy.PreviousImage
+			Expect(k8sClient.Update(ctx, webapp)).To(Succeed())
+			reconcilePolicy()
+			expectImages(image+":1.0", image+":1.0")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			condition := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionImageUpToDate)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("Held"))
+
+			By("Updating again once a newer tag is published")
+			server.Push("team/web", "1.2", "web 1.2")
+			clock.SetTime(clock.Now().Add(10 * time.Minute))
+			reconcilePolicy()
+			expectImages(image+":1.2", image+":1.2")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(webapp.Status.ImagePolicy.PreviousImage).To(Equal(image + ":1.0"))
+
+			Expect(k8sClient.Delete(ctx, webapp)).To(Succeed())
+			finalizeWebApp(ctx, typeNamespacedName)
+		})
+
+		It("should not poll a registry outside the allowed registries", func() {
+			name := types.NamespacedName{Name: "disallowed-image-policy-webapp", Namespace: "default"}
+			DeferCleanup(deleteWebApp, ctx, name)
+
+			polled := false
+			reconciler := newReconciler()
+			reconciler.AllowedRegistries = []string{"ghcr.io/example"}
+			reconciler.TagLister = tagListerFunc(func(context.Context, string) ([]string, error) {
+				polled = true
+				return nil, nil
 			})
+			webapp := &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: name.Name, Namespace: name.Namespace},
+				Spec: webappv1.WebAppSpec{
+					Image:       "ghcr.io.evil.com/example/web:1.0",
+					Replicas:    ptr.To[int32](1),
+					ImagePolicy: &webappv1.ImagePolicySpec{Semver: ">=1.0.0"},
+				},
+			}
+			createWebApp(ctx, reconciler, webapp)
+
+			Expect(polled).To(BeFalse())
+			condition := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionImageUpToDate)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("RegistryNotAllowed"))
+		})
+	})
+
+	Context("When the WebApp references a WebAppTemplate", func() {
+		ctx := context.Background()
+		typeNamespacedName := types.NamespacedName{Name: "templated-webapp", Namespace: "default"}
+		templateName := types.NamespacedName{Name: "base-template", Namespace: "default"}
+
+		It("should wait for the template and merge it under the WebApp's own fields", func() {
+			Expect(k8sClient.Create(ctx, &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec: webappv1.WebAppSpec{
+					Image:       "nginx:latest",
+					Replicas:    ptr.To[int32](1),
+					ConfigData:  map[string]string{"LOG_LEVEL": "debug"},
+					TemplateRef: &webappv1.WebAppTemplateReference{Name: templateName.Name},
+				},
+			})).To(Succeed())
+			reconcileWebApp(ctx, typeNamespacedName)
+
+			By("Checking nothing is created without the template")
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			condition := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionTemplateApplied)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal("TemplateNotFound"))
+			err := k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+
+			By("Creating the template")
+			template := &webappv1.WebAppTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: templateName.Name, Namespace: templateName.Namespace},
+				Spec: webappv1.WebAppTemplateSpec{
+					ConfigData: map[string]string{"LOG_LEVEL": "info", "REGION": "eu"},
+					Resources: &corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+					},
+					ReadinessProbe: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{
+						HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt32(80)},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, template)).To(Succeed())
+			reconcileWebApp(ctx, typeNamespacedName)
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(webapp.Status.Conditions, webappv1.ConditionTemplateApplied)).To(BeTrue())
+			Expect(webapp.Spec.Resources).To(BeNil())
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deploy)).To(Succeed())
+			container := deploy.Spec.Template.Spec.Containers[0]
+			Expect(container.ReadinessProbe).NotTo(BeNil())
+			Expect(container.Resources.Requests.Cpu().String()).To(Equal("100m"))
+			configMap := &corev1.ConfigMap{}
+			configMapName := types.NamespacedName{Namespace: "default", Name: typeNamespacedName.Name + resources.ConfigMapSuffix}
+			Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(Equal(map[string]string{"LOG_LEVEL": "debug", "REGION": "eu"}))
+
+			By("Changing the template")
+			Expect(k8sClient.Get(ctx, templateName, template)).To(Succeed())
+			template.Spec.ConfigData["REGION"] = "us"
+			Expect(k8sClient.Update(ctx, template)).To(Succeed())
+			reconcileWebApp(ctx, typeNamespacedName)
+			Expect(k8sClient.Get(ctx, configMapName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue("REGION", "us"))
+
+			Expect(k8sClient.Delete(ctx, webapp)).To(Succeed())
+			finalizeWebApp(ctx, typeNamespacedName)
+			Expect(k8sClient.Delete(ctx, template)).To(Succeed())
+		})
+	})
+
+	Context("When the WebApp spec is invalid", func() {
+		ctx := context.Background()
+
+		newWebApp := func(name string, mutate func(*webappv1.WebAppSpec)) *webappv1.WebApp {
+			webapp := &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       webappv1.WebAppSpec{Image: "nginx:latest", Replicas: ptr.To[int32](1)},
+			}
+			mutate(&webapp.Spec)
+			return webapp
+		}
+
+		DescribeTable("should be rejected by the API server",
+			func(mutate func(*webappv1.WebAppSpec), message string) {
+				err := k8sClient.Create(ctx, newWebApp("invalid-webapp", mutate))
+				Expect(errors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+				Expect(err.Error()).To(ContainSubstring(message))
+			},
+			Entry("empty image", func(spec *webappv1.WebAppSpec) {
+				spec.Image = ""
+			}, "spec.image"),
+			Entry("negative replicas", func(spec *webappv1.WebAppSpec) {
+				spec.Replicas = ptr.To[int32](-1)
+			}, "spec.replicas"),
+			Entry("ingress path without leading slash", func(spec *webappv1.WebAppSpec) {
+				spec.Ingress = &webappv1.IngressSpec{Enabled: true, Host: "app.example.com", Path: "main"}
+			}, "spec.ingress.path"),
+			Entry("ingress port out of range", func(spec *webappv1.WebAppSpec) {
+				spec.Ingress = &webappv1.IngressSpec{Enabled: true, Port: 70000}
+			}, "spec.ingress.port"),
+			Entry("tls without host", func(spec *webappv1.WebAppSpec) {
+				spec.Ingress = &webappv1.IngressSpec{Enabled: true, TLS: true}
+			}, "tls requires host"),
+			Entry("duplicate storage names", func(spec *webappv1.WebAppSpec) {
+				spec.Storage = []webappv1.StorageSpec{
+					{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi")},
+					{Name: "data", MountPath: "/cache", Size: resource.MustParse("1Gi")},
+				}
+			}, "Duplicate value"),
+			Entry("minAvailable and maxUnavailable together", func(spec *webappv1.WebAppSpec) {
+				spec.PodDisruptionBudget = &webappv1.PodDisruptionBudgetSpec{
+					MinAvailable:   ptr.To(intstr.FromInt32(1)),
+					MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+				}
+			}, "mutually exclusive"),
+			Entry("gateway routing without gateway", func(spec *webappv1.WebAppSpec) {
+				spec.Routing = &webappv1.RoutingSpec{Type: webappv1.RoutingTypeGateway}
+			}, "gateway is required"),
+			Entry("schedule without replicas or hibernate", func(spec *webappv1.WebAppSpec) {
+				spec.Schedules = []webappv1.ScheduleSpec{{Name: "night", Start: "0 20 * * *", End: "0 8 * * *"}}
+			}, "a schedule sets replicas or hibernate"),
+			Entry("rules for the default service account", func(spec *webappv1.WebAppSpec) {
+				spec.ServiceAccount = &webappv1.ServiceAccountSpec{
+					Rules: []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"get"}}},
+				}
+			}, "rules require a created or named ServiceAccount"),
+		)
+
+		It("should reject changes to immutable storage fields", func() {
+			typeNamespacedName := types.NamespacedName{Name: "immutable-storage-webapp", Namespace: "default"}
+			Expect(k8sClient.Create(ctx, newWebApp(typeNamespacedName.Name, func(spec *webappv1.WebAppSpec) {
+				spec.Storage = []webappv1.StorageSpec{
+					{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi"), StorageClassName: ptr.To("standard")},
+				}
+			}))).To(Succeed())
+
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			webapp.Spec.Storage[0].StorageClassName = ptr.To("fast")
+			err := k8sClient.Update(ctx, webapp)
+			Expect(errors.IsInvalid(err)).To(BeTrue(), "unexpected error: %v", err)
+			Expect(err.Error()).To(ContainSubstring("storageClassName is immutable"))
+
+			By("Allowing the size to grow")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			webapp.Spec.Storage[0].Size = resource.MustParse("2Gi")
+			Expect(k8sClient.Update(ctx, webapp)).To(Succeed())
+
+			Expect(k8sClient.Delete(ctx, webapp)).To(Succeed())
+		})
+	})
+
+	Context("When the WebApp is deleted", func() {
+		ctx := context.Background()
+		typeNamespacedName := types.NamespacedName{Name: "cleanup-webapp", Namespace: "default"}
+		orphanName := types.NamespacedName{Name: "orphan-webapp", Namespace: "default"}
+
+		AfterEach(func() {
+			deleteWebApp(ctx, typeNamespacedName)
+			deleteWebApp(ctx, orphanName)
+			orphanMeta := metav1.ObjectMeta{Name: orphanName.Name, Namespace: orphanName.Namespace}
+			deleteObjects(ctx,
+				&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "cleanup-webapp-data", Namespace: "default"}},
+				&appsv1.Deployment{ObjectMeta: orphanMeta},
+				&corev1.Service{ObjectMeta: orphanMeta},
+			)
+		})
+
+		It("should wait for its children and release retained storage", func() {
+			Expect(k8sClient.Create(ctx, &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec: webappv1.WebAppSpec{
+					Image:    "nginx:latest",
+					Replicas: ptr.To[int32](1),
+					Storage: []webappv1.StorageSpec{
+						{Name: "cache", MountPath: "/cache", Size: resource.MustParse("1Gi")},
+						{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi"), Retain: true},
+					},
+				},
+			})).To(Succeed())
+			for range 2 {
+				_, err := newReconciler().Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, webapp)).To(Succeed())
+
+			By("reporting the PVC held by pvc-protection as a blocker")
+			result, err := newReconciler().Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(webapp.Status.Phase).To(Equal(webappv1.WebAppPhaseTerminating))
+			Expect(webapp.Status.CleanupBlockers).To(ContainElement("PersistentVolumeClaim/cleanup-webapp-cache: waiting for deletion"))
+
+			By("removing the finalizer once the children are gone")
+			finalizeWebApp(ctx, typeNamespacedName)
+
+			By("keeping the retained PVC without an owner")
+			pvc := &corev1.PersistentVolumeClaim{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "cleanup-webapp-data", Namespace: "default"}, pvc)).To(Succeed())
+			Expect(metav1.GetControllerOf(pvc)).To(BeNil())
+			Expect(pvc.Annotations).To(HaveKeyWithValue(resources.WebAppRetainedBy, "cleanup-webapp"))
+
+			By("re-adopting the retained PVC when the WebApp is recreated")
+			webapp = &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: typeNamespacedName.Name, Namespace: typeNamespacedName.Namespace},
+				Spec: webappv1.WebAppSpec{
+					Image:    "nginx:latest",
+					Replicas: ptr.To[int32](1),
+					Storage: []webappv1.StorageSpec{
+						{Name: "data", MountPath: "/data", Size: resource.MustParse("1Gi"), Retain: true},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, webapp)).To(Succeed())
+			reconcileWebApp(ctx, typeNamespacedName)
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "cleanup-webapp-data", Namespace: "default"}, pvc)).To(Succeed())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, webapp)).To(Succeed())
+			Expect(metav1.IsControlledBy(pvc, webapp)).To(BeTrue())
+			Expect(pvc.Annotations).NotTo(HaveKey(resources.WebAppRetainedBy))
+
+			Expect(k8sClient.Delete(ctx, webapp)).To(Succeed())
+			finalizeWebApp(ctx, typeNamespacedName)
+		})
+
+		It("should orphan the serving resources when the orphan annotation is set", func() {
+			name := orphanName
+			Expect(k8sClient.Create(ctx, &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name.Name,
+					Namespace:   name.Namespace,
+					Annotations: map[string]string{webappv1.OrphanAnnotation: "true"},
+				},
+				Spec: webappv1.WebAppSpec{Image: "nginx:latest", Replicas: ptr.To[int32](1)},
+			})).To(Succeed())
+			for range 2 {
+				_, err := newReconciler().Reconcile(ctx, reconcile.Request{NamespacedName: name})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			webapp := &webappv1.WebApp{}
+			Expect(k8sClient.Get(ctx, name, webapp)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, webapp)).To(Succeed())
+			finalizeWebApp(ctx, name)
+
+			deploy := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, name, deploy)).To(Succeed())
+			Expect(deploy.OwnerReferences).To(BeEmpty())
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, name, service)).To(Succeed())
+			Expect(service.OwnerReferences).To(BeEmpty())
 		})
 	})
 })
+
+// reconcileWebApp runs the reconciler twice, the first run only adds the finalizer.
+func reconcileWebApp(ctx context.Context, name types.NamespacedName) {
+	for range 2 {
+		_, err := newReconciler().Reconcile(ctx, reconcile.Request{NamespacedName: name})
+		Expect(err).NotTo(HaveOccurred())
+	}
+}
+
+// createWebApp creates the WebApp and reconciles it, see reconcileAndGet.
+func createWebApp(ctx context.Context, reconciler *WebAppReconciler, webapp *webappv1.WebApp) reconcile.Result {
+	Expect(k8sClient.Create(ctx, webapp)).To(Succeed())
+	return reconcileAndGet(ctx, reconciler, webapp)
+}
+
+// reconcileAndGet runs the reconciler twice, the first run only adds the
+// finalizer, and reads the WebApp back. It returns the result of the last run.
+func reconcileAndGet(ctx context.Context, reconciler *WebAppReconciler, webapp *webappv1.WebApp) reconcile.Result {
+	var result reconcile.Result
+	for range 2 {
+		var err error
+		result, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(webapp)})
+		Expect(err).NotTo(HaveOccurred())
+	}
+	Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(webapp), webapp)).To(Succeed())
+	return result
+}
+
+// deleteWebApp removes the finalizer and deletes the WebApp without running
+// the cleanup, so that a failed spec does not leave it behind for the next.
+func deleteWebApp(ctx context.Context, name types.NamespacedName) {
+	webapp := &webappv1.WebApp{}
+	err := k8sClient.Get(ctx, name, webapp)
+	if errors.IsNotFound(err) {
+		return
+	}
+	Expect(err).NotTo(HaveOccurred())
+	if controllerutil.RemoveFinalizer(webapp, resources.WebAppFinalizer) {
+		Expect(k8sClient.Update(ctx, webapp)).To(Succeed())
+	}
+	Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, webapp))).To(Succeed())
+}
+
+// deleteObjects deletes the objects left over by a spec and clears their
+// finalizers, e.g. pvc-protection, which nothing removes in envtest.
+func deleteObjects(ctx context.Context, objs ...client.Object) {
+	for _, obj := range objs {
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, obj))).To(Succeed())
+		err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		Expect(err).NotTo(HaveOccurred())
+		if len(obj.GetFinalizers()) > 0 {
+			obj.SetFinalizers(nil)
+			Expect(client.IgnoreNotFound(k8sClient.Update(ctx, obj))).To(Succeed())
+		}
+	}
+}
+
+// finalizeWebApp drives the cleanup of a deleted WebApp until it is gone.
+// envtest runs no kube-controller-manager, so the pvc-protection finalizer
+// is removed here instead of by the PVC protection controller.
+func finalizeWebApp(ctx context.Context, name types.NamespacedName) {
+	Eventually(func(g Gomega) {
+		pvcs := &corev1.PersistentVolumeClaimList{}
+		g.Expect(k8sClient.List(ctx, pvcs, client.InNamespace(name.Namespace))).To(Succeed())
+		for i := range pvcs.Items {
+			pvc := &pvcs.Items[i]
+			if pvc.DeletionTimestamp != nil && len(pvc.Finalizers) > 0 {
+				pvc.Finalizers = nil
+				g.Expect(k8sClient.Update(ctx, pvc)).To(Succeed())
+			}
+		}
+		_, err := newReconciler().Reconcile(ctx, reconcile.Request{NamespacedName: name})
+		g.Expect(err).NotTo(HaveOccurred())
+		err = k8sClient.Get(ctx, name, &webappv1.WebApp{})
+		g.Expect(errors.IsNotFound(err)).To(BeTrue())
+	}).Should(Succeed())
+}
+
+// tagListerFunc adapts a function to registry.TagLister.
+type tagListerFunc func(ctx context.Context, image string) ([]string, error)
+
+func (f tagListerFunc) ListTags(ctx context.Context, image string) ([]string, error) {
+	return f(ctx, image)
+}
+
+func newReconciler() *WebAppReconciler {
+	return &WebAppReconciler{
+		Client:              k8sClient,
+		Scheme:              k8sClient.Scheme(),
+		GatewayAPIAvailable: true,
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/registry"
+	"github.com/hoon77/crd-operator/internal/pkg/resources"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	defaultImagePollInterval = 5 * time.Minute
+	minImagePollInterval     = time.Minute
+)
+
+// updateImage lists the tags of spec.image when spec.imagePolicy is due for
+// a poll and moves spec.image to the newest matching one. Each new tag is
+// applied once, so that setting spec.image back to
+// status.imagePolicy.previousImage sticks until a newer tag is published.
+// It returns whether spec.image was updated and when to poll next.
+func (r *WebAppReconciler) updateImage(ctx context.Context, webapp *webappv1.WebApp) (bool, time.Duration, error) {
+	policy := webapp.Spec.ImagePolicy
+	if policy == nil {
+		webapp.Status.ImagePolicy = nil
+		meta.RemoveStatusCondition(&webapp.Status.Conditions, webappv1.ConditionImageUpToDate)
+		return false, 0, nil
+	}
+	if r.TagLister == nil {
+		setImageUpToDateCondition(webapp, metav1.ConditionUnknown, "PollingDisabled",
+			"spec.imagePolicy is ignored, the operator does not poll image tags")
+		return false, 0, nil
+	}
+	// never contact a registry that resolveImage would reject
+	if len(r.AllowedRegistries) > 0 && len(registry.DisallowedImages([]string{webapp.Spec.Image}, r.AllowedRegistries)) > 0 {
+		setImageUpToDateCondition(webapp, metav1.ConditionFalse, "RegistryNotAllowed",
+			fmt.Sprintf("image %s is not from an allowed registry", webapp.Spec.Image))
+		return false, 0, nil
+	}
+	interval := defaultImagePollInterval
+	if policy.Interval != nil {
+		interval = max(policy.Interval.Duration, minImagePollInterval)
+	}
+	if webapp.Status.ImagePolicy == nil {
+		webapp.Status.ImagePolicy = &webappv1.ImagePolicyStatus{}
+	}
+	status := webapp.Status.ImagePolicy
+
+	// poll right away when the spec changed since the last poll
+	now := r.now()
+	condition := meta.FindStatusCondition(webapp.Status.Conditions, webappv1.ConditionImageUpToDate)
+	if condition != nil && condition.ObservedGeneration == webapp.Generation && status.LastPollTime != nil {
+		if next := status.LastPollTime.Add(interval).Sub(now); next > 0 {
+			return false, next, nil
+		}
+	}
+	status.LastPollTime = &metav1.Time{Time: now}
+
+	tags, err := r.TagLister.ListTags(ctx, webapp.Spec.Image)
+	var tag string
+	if err == nil {
+		tag, err = registry.LatestTag(tags, policy.Semver, policy.Pattern)
+	}
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "failed to poll the image tags")
+		setImageUpToDateCondition(webapp, metav1.ConditionFalse, "PollFailed", err.Error())
+		return false, interval, nil
+	}
+	if tag == "" {
+		setImageUpToDateCondition(webapp, metav1.ConditionFalse, "NoMatchingTag", "No tag matches the image policy")
+		return false, interval, nil
+	}
+
+	latest := registry.WithTag(webapp.Spec.Image, tag)
+	switch {
+	case latest == webapp.Spec.Image:
+		status.LatestImage = latest
+		setImageUpToDateCondition(webapp, metav1.ConditionTrue, "UpToDate", "spec.image is the newest image matching the policy")
+		return false, interval, nil
+	case latest == status.LatestImage:
+		setImageUpToDateCondition(webapp, metav1.ConditionFalse, "Held",
+			fmt.Sprintf("spec.image is kept until a tag newer than %s is published", latest))
+		return false, interval, nil
+	}
+
+	// patch only the image, the in-memory spec carries the template fields
+	previous := webapp.Spec.Image
+	base := webapp.DeepCopy()
+	webapp.Spec.Image = latest
+	updated := webapp.Status.DeepCopy()
+	updated.ImagePolicy.LatestImage = latest
+	updated.ImagePolicy.PreviousImage = previous
+	updated.ImagePolicy.LastUpdateTime = &metav1.Time{Time: now}
+	if err := r.Patch(ctx, webapp, client.MergeFrom(base)); err != nil {
+		// e.g. rejected by a WebAppPolicy, try again on the next poll
+		logf.FromContext(ctx).Error(err, "failed to update the image", "image", latest)
+		webapp.Spec.Image = previous
+		setImageUpToDateCondition(webapp, metav1.ConditionFalse, "UpdateFailed", err.Error())
+		return false, interval, nil
+	}
+	logf.FromContext(ctx).Info("Updated image", "previous", previous, "image", latest)
+	webapp.Status = *updated
+	setImageUpToDateCondition(webapp, metav1.ConditionTrue, "Updated", fmt.Sprintf("Updated spec.image from %s to %s", previous, latest))
+	return true, interval, r.Status().Update(ctx, webapp)
+}
+
+func setImageUpToDateCondition(webapp *webappv1.WebApp, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&webapp.Status.Conditions, metav1.Condition{
+		Type:               webappv1.ConditionImageUpToDate,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: webapp.Generation,
+	})
+}
+
+// resolveImage checks the images against AllowedRegistries and, with an
+// ImageResolver, pins spec.image of the in-memory WebApp to its digest. It
+// returns false when the children must be left untouched.
+func (r *WebAppReconciler) resolveImage(ctx context.Context, webapp *webappv1.WebApp, oldStatus *webappv1.WebAppStatus) (bool, error) {
+	if len(r.AllowedRegistries) > 0 {
+		if disallowed := registry.DisallowedImages(resources.WebAppImages(webapp), r.AllowedRegistries); len(disallowed) > 0 {
+			message := fmt.Sprintf("images are not from an allowed registry: %s", strings.Join(disallowed, ", "))
+			return false, r.reportImageNotResolved(ctx, webapp, oldStatus, "RegistryNotAllowed", message)
+		}
+	}
+	if r.ImageResolver == nil {
+		webapp.Status.ResolvedImage = ""
+		meta.RemoveStatusCondition(&webapp.Status.Conditions, webappv1.ConditionImageResolved)
+		return true, nil
+	}
+
+	// keep the digest until spec.image changes, so that a moved tag does not
+	// roll the Deployment behind the user's back
+	resolved := webapp.Status.ResolvedImage
+	if resolved != webapp.Spec.Image && !strings.HasPrefix(resolved, webapp.Spec.Image+"@") {
+		digest, err := r.ImageResolver.Resolve(ctx, webapp.Spec.Image)
+		if err != nil {
+			if reportErr := r.reportImageNotResolved(ctx, webapp, oldStatus, "ResolveFailed", err.Error()); reportErr != nil {
+				return false, reportErr
+			}
+			return false, err
+		}
+		resolved = registry.Pin(webapp.Spec.Image, digest)
+		logf.FromContext(ctx).Info("Resolved image", "image", webapp.Spec.Image, "resolved", resolved)
+	}
+	webapp.Status.ResolvedImage = resolved
+	meta.SetStatusCondition(&webapp.Status.Conditions, metav1.Condition{
+		Type:               webappv1.ConditionImageResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Resolved",
+		Message:            "The image is pinned to " + resolved,
+		ObservedGeneration: webapp.Generation,
+	})
+	webapp.Spec.Image = resolved
+	return true, nil
+}
+
+// reportImageNotResolved records why the image was rejected or could not be
+// resolved. The children keep running the previously resolved image.
+func (r *WebAppReconciler) reportImageNotResolved(ctx context.Context, webapp *webappv1.WebApp, oldStatus *webappv1.WebAppStatus, reason, message string) error {
+	logf.FromContext(ctx).Info("WebApp image is not resolved", "reason", reason, "message", message)
+	meta.SetStatusCondition(&webapp.Status.Conditions, metav1.Condition{
+		Type:               webappv1.ConditionImageResolved,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: webapp.Generation,
+	})
+	if reflect.DeepEqual(oldStatus, &webapp.Status) {
+		return nil
+	}
+	return r.Status().Update(ctx, webapp)
+}
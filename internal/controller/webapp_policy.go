@@ -0,0 +1,93 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/resources"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// +kubebuilder:rbac:groups=webapp.crdlego.com,resources=webapppolicies,verbs=get;list;watch
+
+// policiesFor returns the WebAppPolicies selecting the namespace of the WebApp.
+func (r *WebAppReconciler) policiesFor(ctx context.Context, webapp *webappv1.WebApp) ([]webappv1.WebAppPolicy, error) {
+	return resources.ListPolicies(ctx, r.Client, webapp.Namespace)
+}
+
+// reportPolicyViolations marks the WebApp as violating its policies and
+// leaves the children as they are until the WebApp or the policies change.
+func (r *WebAppReconciler) reportPolicyViolations(ctx context.Context, webapp *webappv1.WebApp, oldStatus *webappv1.WebAppStatus, violations []string) error {
+	message := strings.Join(violations, "; ")
+	logf.FromContext(ctx).Info("WebApp violates its policies", "violations", message)
+	meta.SetStatusCondition(&webapp.Status.Conditions, metav1.Condition{
+		Type:               webappv1.ConditionPolicyCompliant,
+		Status:             metav1.ConditionFalse,
+		Reason:             "PolicyViolation",
+		Message:            message,
+		ObservedGeneration: webapp.Generation,
+	})
+	meta.SetStatusCondition(&webapp.Status.Conditions, metav1.Condition{
+		Type:               webappv1.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             "PolicyViolation",
+		Message:            "child resources are not updated while the WebApp violates its policies",
+		ObservedGeneration: webapp.Generation,
+	})
+	if reflect.DeepEqual(oldStatus, &webapp.Status) {
+		return nil
+	}
+	return r.Status().Update(ctx, webapp)
+}
+
+func setPolicyCompliantCondition(webapp *webappv1.WebApp, policies []webappv1.WebAppPolicy) {
+	condition := metav1.Condition{
+		Type:               webappv1.ConditionPolicyCompliant,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Compliant",
+		Message:            "The WebApp complies with the WebAppPolicies of its namespace",
+		ObservedGeneration: webapp.Generation,
+	}
+	if len(policies) == 0 {
+		condition.Reason = "NoPolicies"
+		condition.Message = "No WebAppPolicy selects the namespace"
+	}
+	meta.SetStatusCondition(&webapp.Status.Conditions, condition)
+}
+
+// webAppsForPolicy re-evaluates every WebApp when a policy changes; policies
+// change rarely and their namespace selector may match any namespace.
+func (r *WebAppReconciler) webAppsForPolicy(ctx context.Context, _ client.Object) []reconcile.Request {
+	var webapps webappv1.WebAppList
+	if err := r.List(ctx, &webapps); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list WebApps for WebAppPolicy")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(webapps.Items))
+	for _, webapp := range webapps.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&webapp)})
+	}
+	return requests
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/resources"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// templateRefIndex indexes WebApps by the name of their WebAppTemplate.
+const templateRefIndex = "spec.templateRef.name"
+
+// +kubebuilder:rbac:groups=webapp.crdlego.com,resources=webapptemplates,verbs=get;list;watch
+
+// applyTemplate merges the WebAppTemplate of spec.templateRef into the
+// in-memory spec. It returns false when the template is missing, leaving the
+// children untouched until it is created.
+func (r *WebAppReconciler) applyTemplate(ctx context.Context, webapp *webappv1.WebApp, oldStatus *webappv1.WebAppStatus) (bool, error) {
+	ref := webapp.Spec.TemplateRef
+	if ref == nil {
+		meta.RemoveStatusCondition(&webapp.Status.Conditions, webappv1.ConditionTemplateApplied)
+		return true, nil
+	}
+
+	template := &webappv1.WebAppTemplate{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: webapp.Namespace, Name: ref.Name}, template)
+	if errors.IsNotFound(err) {
+		logf.FromContext(ctx).Info("WebAppTemplate not found", "template", ref.Name)
+		meta.SetStatusCondition(&webapp.Status.Conditions, metav1.Condition{
+			Type:               webappv1.ConditionTemplateApplied,
+			Status:             metav1.ConditionFalse,
+			Reason:             "TemplateNotFound",
+			Message:            "WebAppTemplate " + ref.Name + " does not exist",
+			ObservedGeneration: webapp.Generation,
+		})
+		if reflect.DeepEqual(oldStatus, &webapp.Status) {
+			return false, nil
+		}
+		return false, r.Status().Update(ctx, webapp)
+	} else if err != nil {
+		return false, err
+	}
+
+	resources.ApplyTemplate(webapp, template)
+	meta.SetStatusCondition(&webapp.Status.Conditions, metav1.Condition{
+		Type:               webappv1.ConditionTemplateApplied,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Applied",
+		Message:            "Fields not set by the WebApp come from WebAppTemplate " + ref.Name,
+		ObservedGeneration: webapp.Generation,
+	})
+	return true, nil
+}
+
+// indexTemplateRef returns the WebAppTemplate the WebApp references.
+func indexTemplateRef(obj client.Object) []string {
+	webapp, ok := obj.(*webappv1.WebApp)
+	if !ok || webapp.Spec.TemplateRef == nil {
+		return nil
+	}
+	return []string{webapp.Spec.TemplateRef.Name}
+}
+
+// webAppsForTemplate re-reconciles the WebApps referencing the template.
+func (r *WebAppReconciler) webAppsForTemplate(ctx context.Context, template client.Object) []reconcile.Request {
+	var webapps webappv1.WebAppList
+	if err := r.List(ctx, &webapps, client.InNamespace(template.GetNamespace()),
+		client.MatchingFields{templateRefIndex: template.GetName()}); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list WebApps for WebAppTemplate")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(webapps.Items))
+	for _, webapp := range webapps.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&webapp)})
+	}
+	return requests
+}
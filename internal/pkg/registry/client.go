@@ -0,0 +1,253 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Resolver resolves an image reference to the digest of its manifest.
+type Resolver interface {
+	Resolve(ctx context.Context, image string) (string, error)
+}
+
+// TagLister lists the tags of the repository of an image.
+type TagLister interface {
+	ListTags(ctx context.Context, image string) ([]string, error)
+}
+
+// manifestMediaTypes are accepted when resolving a tag, so that the digest
+// of a multi-arch index is returned rather than one of its manifests.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// maxTagPages bounds the pages ListTags follows, so that a registry
+// linking to itself cannot keep the reconciler busy.
+const maxTagPages = 100
+
+// tokenHosts lists the hosts, besides the registry itself, trusted to issue
+// tokens for a registry.
+var tokenHosts = map[string]string{
+	"registry-1.docker.io": "auth.docker.io",
+}
+
+// Client talks to registries over the OCI distribution API with anonymous
+// bearer tokens, so only public repositories can be resolved.
+type Client struct {
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+var (
+	_ Resolver  = &Client{}
+	_ TagLister = &Client{}
+)
+
+// Resolve implements Resolver. Images that already carry a digest are
+// resolved without contacting the registry.
+func (c *Client) Resolve(ctx context.Context, image string) (string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+	resp, err := c.do(ctx, http.MethodHead, ref, "/manifests/"+ref.Tag, strings.Join(manifestMediaTypes, ", "))
+	if err != nil {
+		return "", fmt.Errorf("image %s: %w", image, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if !digestPattern.MatchString(digest) {
+		return "", fmt.Errorf("image %s: registry returned digest %q", image, digest)
+	}
+	return digest, nil
+}
+
+// ListTags implements TagLister, following up to maxTagPages pagination
+// links of the registry.
+func (c *Client) ListTags(ctx context.Context, image string) ([]string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	path := "/tags/list"
+	for pages := 0; path != ""; pages++ {
+		if pages == maxTagPages {
+			return nil, fmt.Errorf("image %s: more than %d pages of tags", image, maxTagPages)
+		}
+		page, next, err := c.listTags(ctx, ref, path)
+		if err != nil {
+			return nil, fmt.Errorf("image %s: %w", image, err)
+		}
+		tags = append(tags, page...)
+		path = next
+	}
+	return tags, nil
+}
+
+// listTags fetches one page of tags and returns the path of the next one.
+func (c *Client) listTags(ctx context.Context, ref Reference, path string) ([]string, string, error) {
+	resp, err := c.do(ctx, http.MethodGet, ref, path, "application/json")
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&body); err != nil {
+		return nil, "", err
+	}
+	// Link: </v2/<repository>/tags/list?last=...&n=...>; rel="next"
+	link, _, _ := strings.Cut(resp.Header.Get("Link"), ";")
+	if link = strings.Trim(strings.TrimSpace(link), "<>"); link == "" {
+		return body.Tags, "", nil
+	}
+	next, err := url.Parse(link)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid Link header %q: %w", link, err)
+	}
+	path, ok := strings.CutPrefix(next.Path, "/v2/"+ref.Repository)
+	if !ok {
+		return nil, "", fmt.Errorf("unexpected Link header %q", link)
+	}
+	return body.Tags, path + "?" + next.RawQuery, nil
+}
+
+// do sends the request to the repository, fetching an anonymous token when
+// the registry asks for one. The caller closes the body of the response.
+func (c *Client) do(ctx context.Context, method string, ref Reference, path, accept string) (*http.Response, error) {
+	host := registryHost(ref.Registry)
+	endpoint := fmt.Sprintf("https://%s/v2/%s%s", host, ref.Repository, path)
+	var token string
+	for {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || token != "" {
+			return nil, fmt.Errorf("%s %s: %s", method, endpoint, resp.Status)
+		}
+		if token, err = c.token(ctx, host, resp.Header.Get("WWW-Authenticate")); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// token fetches an anonymous token from the realm of a Bearer challenge
+// sent by host. The realm must be served over https by host or by its entry
+// in tokenHosts, so that a registry cannot point the operator at arbitrary
+// URLs.
+func (c *Client) token(ctx context.Context, host, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+	values := url.Values{}
+	var realm string
+	for _, param := range splitChallenge(params) {
+		key, value, _ := strings.Cut(param, "=")
+		value = strings.Trim(value, `"`)
+		if key == "realm" {
+			realm = value
+		} else {
+			values.Set(key, value)
+		}
+	}
+	if realm == "" {
+		return "", fmt.Errorf("authentication challenge %q has no realm", challenge)
+	}
+	realmURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid realm %q: %w", realm, err)
+	}
+	if realmURL.Scheme != "https" || (realmURL.Host != host && realmURL.Host != tokenHosts[host]) {
+		return "", fmt.Errorf("realm %q is not served over https by %s", realm, host)
+	}
+
+	query := realmURL.Query()
+	for key := range values {
+		query.Set(key, values.Get(key))
+	}
+	realmURL.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realmURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", realm, resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("GET %s: %w", realm, err)
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	return body.Token, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// registryHost maps Docker Hub to its API host.
+func registryHost(registry string) string {
+	if registry == DockerHub {
+		return "registry-1.docker.io"
+	}
+	return registry
+}
+
+// splitChallenge splits the comma separated parameters of a challenge,
+// ignoring commas inside quotes such as in the scope.
+func splitChallenge(params string) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i, ch := range params {
+		switch {
+		case ch == '"':
+			quoted = !quoted
+		case ch == ',' && !quoted:
+			parts = append(parts, strings.TrimSpace(params[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(params[start:]))
+}
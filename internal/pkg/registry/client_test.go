@@ -0,0 +1,102 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hoon77/crd-operator/internal/pkg/registry/registrytest"
+)
+
+func TestClientResolve(t *testing.T) {
+	server := registrytest.NewServer()
+	defer server.Close()
+	digest := server.Push("team/web", "1.0", "web 1.0")
+
+	client := &Client{HTTPClient: server.Client()}
+	got, err := client.Resolve(context.Background(), server.Host()+"/team/web:1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != digest {
+		t.Errorf("Resolve() = %q, want %q", got, digest)
+	}
+
+	if _, err := client.Resolve(context.Background(), server.Host()+"/team/web:2.0"); err == nil {
+		t.Errorf("Resolve() of a missing tag succeeded")
+	}
+
+	// pinned images are not looked up
+	if got, err := client.Resolve(context.Background(), "unreachable.invalid/web@"+testDigest); err != nil || got != testDigest {
+		t.Errorf("Resolve() of a pinned image = %q, %v", got, err)
+	}
+}
+
+func TestClientListTags(t *testing.T) {
+	server := registrytest.NewServer()
+	defer server.Close()
+	server.PageSize = 2
+	for _, tag := range []string{"1.0", "1.1", "1.2", "latest", "2.0"} {
+		server.Push("team/web", tag, "web "+tag)
+	}
+
+	client := &Client{HTTPClient: server.Client()}
+	got, err := client.ListTags(context.Background(), server.Host()+"/team/web:1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"1.0", "1.1", "1.2", "2.0", "latest"}; !slices.Equal(got, want) {
+		t.Errorf("ListTags() = %v, want %v", got, want)
+	}
+
+	if _, err := client.ListTags(context.Background(), server.Host()+"/team/missing"); err == nil {
+		t.Errorf("ListTags() of a missing repository succeeded")
+	}
+}
+
+func TestClientListTagsPageLimit(t *testing.T) {
+	server := registrytest.NewServer()
+	defer server.Close()
+	server.PageSize = 1
+	for i := range maxTagPages + 1 {
+		tag := fmt.Sprintf("1.%d", i)
+		server.Push("team/web", tag, "web "+tag)
+	}
+
+	client := &Client{HTTPClient: server.Client()}
+	if _, err := client.ListTags(context.Background(), server.Host()+"/team/web"); err == nil {
+		t.Errorf("ListTags() of %d pages succeeded", maxTagPages+1)
+	}
+}
+
+func TestClientRealmQuery(t *testing.T) {
+	server := registrytest.NewServer()
+	defer server.Close()
+	digest := server.Push("team/web", "1.0", "web 1.0")
+	server.Realm = server.URL + "/token?account=anonymous"
+
+	client := &Client{HTTPClient: server.Client()}
+	if got, err := client.Resolve(context.Background(), server.Host()+"/team/web:1.0"); err != nil || got != digest {
+		t.Errorf("Resolve() with a realm query = %q, %v", got, err)
+	}
+}
+
+func TestClientForeignRealm(t *testing.T) {
+	server := registrytest.NewServer()
+	defer server.Close()
+	server.Push("team/web", "1.0", "web 1.0")
+
+	client := &Client{HTTPClient: server.Client()}
+	for _, realm := range []string{
+		"https://metadata.internal/token",
+		strings.Replace(server.URL, "https://", "http://", 1) + "/token",
+	} {
+		server.Realm = realm
+		_, err := client.Resolve(context.Background(), server.Host()+"/team/web:1.0")
+		if err == nil || !strings.Contains(err.Error(), "is not served over https by") {
+			t.Errorf("Resolve() with realm %s = %v, want the realm to be rejected", realm, err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Package registry resolves container image references against OCI
+// distribution registries.
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// DockerHub is the registry of image names without a registry host.
+	DockerHub = "docker.io"
+
+	defaultTag = "latest"
+)
+
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// Reference is a parsed image reference such as nginx:1.27 or
+// ghcr.io/org/app@sha256:....
+type Reference struct {
+	// Registry is the registry host, docker.io when the image names none.
+	Registry string
+	// Repository is the path within the registry, e.g. library/nginx.
+	Repository string
+	// Tag defaults to latest when neither a tag nor a digest is given.
+	Tag string
+	// Digest is set when the image is pinned.
+	Digest string
+}
+
+// ParseReference parses the image the way the container runtimes do: the
+// first path component is a registry host when it contains a dot or a port,
+// or is localhost.
+func ParseReference(image string) (Reference, error) {
+	var ref Reference
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+		if !digestPattern.MatchString(ref.Digest) {
+			return Reference{}, fmt.Errorf("image %s: invalid digest %q", image, ref.Digest)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	if name == "" || ref.Tag == "" && strings.HasSuffix(image, ":") {
+		return Reference{}, fmt.Errorf("image %s: invalid reference", image)
+	}
+
+	ref.Registry, ref.Repository = DockerHub, name
+	if host, path, ok := strings.Cut(name, "/"); ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		ref.Registry, ref.Repository = host, path
+	}
+	if ref.Registry == DockerHub && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	if ref.Repository == "" || ref.Repository != strings.ToLower(ref.Repository) {
+		return Reference{}, fmt.Errorf("image %s: invalid repository %q", image, ref.Repository)
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+	return ref, nil
+}
+
+// Name returns the registry and repository, e.g. docker.io/library/nginx.
+func (r Reference) Name() string {
+	return r.Registry + "/" + r.Repository
+}
+
+// Pin appends the digest to the image, keeping its tag for readability.
+// Images that already carry a digest are returned as they are.
+func Pin(image, digest string) string {
+	if strings.Contains(image, "@") {
+		return image
+	}
+	return image + "@" + digest
+}
+
+// WithTag replaces the tag and drops the digest of the image, keeping its
+// name as written.
+func WithTag(image, tag string) string {
+	name, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return name + ":" + tag
+}
+
+// DisallowedImages returns the images that are not from one of the allowed
+// registries. An allowed entry is a registry host (docker.io) or a
+// repository prefix within one (ghcr.io/example). Images that cannot be
+// parsed are disallowed.
+func DisallowedImages(images []string, allowed []string) []string {
+	var disallowed []string
+	for _, image := range images {
+		ref, err := ParseReference(image)
+		if err != nil || !isAllowed(ref, allowed) {
+			disallowed = append(disallowed, image)
+		}
+	}
+	return disallowed
+}
+
+func isAllowed(ref Reference, allowed []string) bool {
+	name := ref.Name()
+	for _, prefix := range allowed {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if name == prefix || strings.HasPrefix(name, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
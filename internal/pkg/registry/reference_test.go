@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"slices"
+	"testing"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image   string
+		want    Reference
+		wantErr bool
+	}{
+		{image: "nginx", want: Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{image: "nginx:1.27", want: Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.27"}},
+		{image: "bitnami/nginx:1.27", want: Reference{Registry: "docker.io", Repository: "bitnami/nginx", Tag: "1.27"}},
+		{image: "ghcr.io/example/web:v2", want: Reference{Registry: "ghcr.io", Repository: "example/web", Tag: "v2"}},
+		{image: "localhost:5000/web", want: Reference{Registry: "localhost:5000", Repository: "web", Tag: "latest"}},
+		{image: "localhost/web:dev", want: Reference{Registry: "localhost", Repository: "web", Tag: "dev"}},
+		{image: "nginx@" + testDigest, want: Reference{Registry: "docker.io", Repository: "library/nginx", Digest: testDigest}},
+		{image: "nginx:1.27@" + testDigest, want: Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.27", Digest: testDigest}},
+		{image: "nginx@sha256:abc", wantErr: true},
+		{image: "nginx:", wantErr: true},
+		{image: "Nginx", wantErr: true},
+		{image: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, err := ParseReference(tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseReference() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPin(t *testing.T) {
+	if got := Pin("nginx:1.27", testDigest); got != "nginx:1.27@"+testDigest {
+		t.Errorf("Pin() = %q", got)
+	}
+	if got := Pin("nginx@"+testDigest, "sha256:other"); got != "nginx@"+testDigest {
+		t.Errorf("Pin() of a pinned image = %q", got)
+	}
+}
+
+func TestWithTag(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                             "nginx:1.28",
+		"nginx:1.27":                        "nginx:1.28",
+		"localhost:5000/web:1.27":           "localhost:5000/web:1.28",
+		"nginx:1.27@" + testDigest:          "nginx:1.28",
+		"ghcr.io/example/web@" + testDigest: "ghcr.io/example/web:1.28",
+	}
+	for image, want := range tests {
+		if got := WithTag(image, "1.28"); got != want {
+			t.Errorf("WithTag(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestDisallowedImages(t *testing.T) {
+	images := []string{
+		"nginx:latest",
+		"docker.io/bitnami/nginx",
+		"ghcr.io/example/web:v2",
+		"ghcr.io/other/web:v2",
+		"registry.example.com/web",
+		"Invalid",
+	}
+	got := DisallowedImages(images, []string{"docker.io/library", "ghcr.io/example/", "registry.example.com"})
+	want := []string{"docker.io/bitnami/nginx", "ghcr.io/other/web:v2", "Invalid"}
+	if !slices.Equal(got, want) {
+		t.Errorf("DisallowedImages() = %v, want %v", got, want)
+	}
+}
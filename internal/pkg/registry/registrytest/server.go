@@ -0,0 +1,138 @@
+// Package registrytest provides an in-memory OCI distribution registry for
+// tests. It serves the manifest and tag list endpoints behind anonymous
+// bearer tokens, like the public registries do.
+package registrytest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const token = "registrytest-token"
+
+// Server is a registry holding tags and their digests, listening on TLS
+// with a self-signed certificate trusted by the Client it returns.
+type Server struct {
+	*httptest.Server
+
+	// PageSize paginates the tag lists when the client does not ask for a
+	// page size. Zero lists every tag at once.
+	PageSize int
+
+	// Realm overrides the token endpoint advertised in the challenges, which
+	// defaults to the /token path of the server.
+	Realm string
+
+	mu   sync.RWMutex
+	tags map[string]map[string]string
+}
+
+// NewServer starts a registry. Close it when the test is done.
+func NewServer() *Server {
+	s := &Server{tags: map[string]map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", s.serveToken)
+	mux.HandleFunc("/v2/", s.serveRegistry)
+	s.Server = httptest.NewTLSServer(mux)
+	return s
+}
+
+// Host is the registry host to prefix image names with, e.g. 127.0.0.1:40123.
+func (s *Server) Host() string {
+	return strings.TrimPrefix(s.URL, "https://")
+}
+
+// Push points the tag of the repository at a digest derived from content
+// and returns that digest.
+func (s *Server) Push(repository, tag, content string) string {
+	sum := sha256.Sum256([]byte(content))
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tags[repository] == nil {
+		s.tags[repository] = map[string]string{}
+	}
+	s.tags[repository][tag] = digest
+	return digest
+}
+
+// serveToken requires the service of the challenge, like the registries do.
+func (s *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("service") != "registrytest" {
+		http.Error(w, "unknown service", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
+
+func (s *Server) serveRegistry(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+token {
+		realm := s.Realm
+		if realm == "" {
+			realm = s.URL + "/token"
+		}
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s",service="registrytest"`, realm))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	if repository, ok := strings.CutSuffix(path, "/tags/list"); ok {
+		s.serveTags(w, r, repository)
+		return
+	}
+	repository, reference, ok := strings.Cut(path, "/manifests/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.RLock()
+	digest, found := s.tags[repository][reference]
+	s.mu.RUnlock()
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.oci.image.index.v1+json")
+	w.Header().Set("Docker-Content-Digest", digest)
+}
+
+// serveTags lists the tags in lexical order after last, n or PageSize at a
+// time, and links to the next page like the distribution API.
+func (s *Server) serveTags(w http.ResponseWriter, r *http.Request, repository string) {
+	s.mu.RLock()
+	tags, found := s.tags[repository]
+	names := slices.Sorted(maps.Keys(tags))
+	s.mu.RUnlock()
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	if last := r.URL.Query().Get("last"); last != "" {
+		i, found := slices.BinarySearch(names, last)
+		if found {
+			i++
+		}
+		names = names[i:]
+	}
+	n := s.PageSize
+	if size, err := strconv.Atoi(r.URL.Query().Get("n")); err == nil {
+		n = size
+	}
+	if n > 0 && n < len(names) {
+		names = names[:n]
+		w.Header().Set("Link", fmt.Sprintf(`</v2/%s/tags/list?n=%d&last=%s>; rel="next"`, repository, n, url.QueryEscape(names[n-1])))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"name": repository, "tags": names})
+}
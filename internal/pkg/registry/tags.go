@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+)
+
+// LatestTag returns the newest of the tags selected by either a semver range
+// or a regular expression, or "" when none matches.
+//
+// With a range, tags are compared as versions; tags that are not versions,
+// such as latest, and pre-releases are skipped. With a pattern, the whole tag
+// must match and tags are compared by the first capture group, or the whole
+// tag, numerically when both are integers and lexically otherwise.
+func LatestTag(tags []string, semverRange, pattern string) (string, error) {
+	switch {
+	case semverRange != "" && pattern != "":
+		return "", fmt.Errorf("semver and pattern are mutually exclusive")
+	case semverRange != "":
+		return latestVersion(tags, semverRange)
+	case pattern != "":
+		return latestMatch(tags, pattern)
+	default:
+		return "", fmt.Errorf("either semver or pattern is required")
+	}
+}
+
+func latestVersion(tags []string, semverRange string) (string, error) {
+	inRange, err := semver.ParseRange(semverRange)
+	if err != nil {
+		return "", fmt.Errorf("invalid semver range %q: %w", semverRange, err)
+	}
+	var latest string
+	var latestVersion semver.Version
+	for _, tag := range tags {
+		version, err := semver.ParseTolerant(tag)
+		if err != nil || len(version.Pre) > 0 || !inRange(version) {
+			continue
+		}
+		if latest == "" || version.GT(latestVersion) {
+			latest, latestVersion = tag, version
+		}
+	}
+	return latest, nil
+}
+
+func latestMatch(tags []string, pattern string) (string, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	var latest, latestKey string
+	for _, tag := range tags {
+		match := re.FindStringSubmatch(tag)
+		if match == nil {
+			continue
+		}
+		key := match[0]
+		if len(match) > 1 {
+			key = match[1]
+		}
+		if latest == "" || compareKeys(key, latestKey) > 0 {
+			latest, latestKey = tag, key
+		}
+	}
+	return latest, nil
+}
+
+func compareKeys(a, b string) int {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	if errA == nil && errB == nil {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(a, b)
+}
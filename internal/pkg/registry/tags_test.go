@@ -0,0 +1,36 @@
+package registry
+
+import "testing"
+
+func TestLatestTag(t *testing.T) {
+	tags := []string{"latest", "1.2.0", "v1.10.1", "1.9.3", "2.0.0", "2.1.0-rc.1", "main-20", "main-100", "main-abc"}
+	tests := []struct {
+		name    string
+		semver  string
+		pattern string
+		want    string
+		wantErr bool
+	}{
+		{name: "range", semver: ">=1.0.0 <2.0.0", want: "v1.10.1"},
+		{name: "wildcard", semver: "2.x", want: "2.0.0"},
+		{name: "no match", semver: ">=3.0.0", want: ""},
+		{name: "pattern ordered numerically", pattern: `main-(\d+)`, want: "main-100"},
+		{name: "pattern ordered lexically", pattern: `main-.*`, want: "main-abc"},
+		{name: "pattern matches the whole tag", pattern: `1\.9`, want: ""},
+		{name: "invalid range", semver: "one", wantErr: true},
+		{name: "invalid pattern", pattern: "(", wantErr: true},
+		{name: "both", semver: "1.x", pattern: ".*", wantErr: true},
+		{name: "neither", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LatestTag(tags, tt.semver, tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LatestTag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LatestTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
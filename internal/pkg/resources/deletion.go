@@ -0,0 +1,43 @@
+package resources
+
+import (
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+)
+
+// WebAppRetainedBy marks a child kept by the Retain deletion policy with the
+// name of the WebApp it belonged to.
+const WebAppRetainedBy = "webapp.crdlego.com/retained-by"
+
+// orphanKinds are the children covered by the orphan annotation: the ones
+// that serve traffic.
+var orphanKinds = map[string]bool{
+	"Deployment": true,
+	"Service":    true,
+	"ConfigMap":  true,
+	"Ingress":    true,
+}
+
+// DeletionPolicyFor returns what the finalizer does with the child of the
+// given kind and name. The orphan annotation wins over spec.deletionPolicy,
+// and a retained spec.storage entry wins over the PersistentVolumeClaim policy.
+func DeletionPolicyFor(webapp *webappv1.WebApp, kind, name string) webappv1.DeletionPolicyType {
+	if webapp.Annotations[webappv1.OrphanAnnotation] == "true" && orphanKinds[kind] {
+		return webappv1.DeletionPolicyOrphan
+	}
+	if kind == "PersistentVolumeClaim" {
+		for _, storage := range webapp.Spec.Storage {
+			if storage.Retain && PersistentVolumeClaimName(webapp, storage) == name {
+				return webappv1.DeletionPolicyRetain
+			}
+		}
+	}
+	if policy := webapp.Spec.DeletionPolicy; policy != nil {
+		if p, ok := policy.Resources[kind]; ok && p != "" {
+			return p
+		}
+		if policy.Default != "" {
+			return policy.Default
+		}
+	}
+	return webappv1.DeletionPolicyDelete
+}
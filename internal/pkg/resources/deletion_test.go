@@ -0,0 +1,82 @@
+package resources
+
+import (
+	"testing"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDeletionPolicyFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		webapp webappv1.WebApp
+		kind   string
+		child  string
+		want   webappv1.DeletionPolicyType
+	}{
+		{
+			name:  "defaults to delete",
+			kind:  "Deployment",
+			child: "app",
+			want:  webappv1.DeletionPolicyDelete,
+		},
+		{
+			name: "per kind policy",
+			webapp: webappv1.WebApp{Spec: webappv1.WebAppSpec{DeletionPolicy: &webappv1.DeletionPolicySpec{
+				Default:   webappv1.DeletionPolicyDelete,
+				Resources: map[string]webappv1.DeletionPolicyType{"Service": webappv1.DeletionPolicyOrphan},
+			}}},
+			kind:  "Service",
+			child: "app",
+			want:  webappv1.DeletionPolicyOrphan,
+		},
+		{
+			name: "default policy",
+			webapp: webappv1.WebApp{Spec: webappv1.WebAppSpec{DeletionPolicy: &webappv1.DeletionPolicySpec{
+				Default: webappv1.DeletionPolicyRetain,
+			}}},
+			kind:  "ConfigMap",
+			child: "app-config",
+			want:  webappv1.DeletionPolicyRetain,
+		},
+		{
+			name: "orphan annotation overrides the spec",
+			webapp: webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{webappv1.OrphanAnnotation: "true"}},
+				Spec: webappv1.WebAppSpec{DeletionPolicy: &webappv1.DeletionPolicySpec{
+					Default: webappv1.DeletionPolicyDelete,
+				}},
+			},
+			kind:  "Ingress",
+			child: "app",
+			want:  webappv1.DeletionPolicyOrphan,
+		},
+		{
+			name: "orphan annotation leaves other kinds alone",
+			webapp: webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{webappv1.OrphanAnnotation: "true"}},
+			},
+			kind:  "NetworkPolicy",
+			child: "app",
+			want:  webappv1.DeletionPolicyDelete,
+		},
+		{
+			name: "retained storage",
+			webapp: webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: "app"},
+				Spec:       webappv1.WebAppSpec{Storage: []webappv1.StorageSpec{{Name: "data", Retain: true}}},
+			},
+			kind:  "PersistentVolumeClaim",
+			child: "app-data",
+			want:  webappv1.DeletionPolicyRetain,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeletionPolicyFor(&tt.webapp, tt.kind, tt.child); got != tt.want {
+				t.Errorf("DeletionPolicyFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
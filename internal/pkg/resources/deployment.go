@@ -9,11 +9,23 @@ import (
 )
 
 const (
-	WebAppFinalizer = "webapp.finalizers.crdlego.com"
-	WebAppHashKey   = "webapp.crdlego.com/config-hash"
+	WebAppFinalizer     = "webapp.finalizers.crdlego.com"
+	WebAppHashKey       = "webapp.crdlego.com/config-hash"
+	WebAppTemplateHash  = "webapp.crdlego.com/template-hash"
+	WebAppContainerName = "webapp"
+	WebAppContainerPort = 80
 )
 
-func BuildDeployment(webapp *webappv1.WebApp) *appsv1.Deployment {
+// DeploymentOptions carries operator-wide settings that affect the Deployment.
+type DeploymentOptions struct {
+	// HardenedSecurityDefaults applies ApplyHardenedSecurityDefaults to the pod template.
+	HardenedSecurityDefaults bool
+	// Policies are the WebAppPolicies selecting the namespace, whose defaults
+	// are merged into the pod template.
+	Policies []webappv1.WebAppPolicy
+}
+
+func BuildDeployment(webapp *webappv1.WebApp, opts DeploymentOptions) *appsv1.Deployment {
 	labels := map[string]string{
 		"app": webapp.Name,
 	}
@@ -22,7 +34,9 @@ func BuildDeployment(webapp *webappv1.WebApp) *appsv1.Deployment {
 		WebAppHashKey: utils.HashMapString(webapp.Spec.ConfigData),
 	}
 
-	return &appsv1.Deployment{
+	storageVolumes, storageMounts := BuildStorageVolumes(webapp)
+
+	deploy := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      webapp.Name,
 			Namespace: webapp.Namespace,
@@ -39,9 +53,10 @@ func BuildDeployment(webapp *webappv1.WebApp) *appsv1.Deployment {
 					Annotations: annotations,
 				},
 				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
+					InitContainers: BuildInitContainers(webapp),
+					Containers: append([]corev1.Container{
 						{
-							Name:  "webapp",
+							Name:  WebAppContainerName,
 							Image: webapp.Spec.Image,
 							EnvFrom: []corev1.EnvFromSource{
 								{
@@ -54,13 +69,70 @@ func BuildDeployment(webapp *webappv1.WebApp) *appsv1.Deployment {
 							},
 							Ports: []corev1.ContainerPort{
 								{
-									ContainerPort: 80,
+									ContainerPort: WebAppContainerPort,
 								},
 							},
+							VolumeMounts:    append(append([]corev1.VolumeMount{}, webapp.Spec.VolumeMounts...), storageMounts...),
+							LivenessProbe:   webapp.Spec.LivenessProbe.DeepCopy(),
+							ReadinessProbe:  webapp.Spec.ReadinessProbe.DeepCopy(),
+							SecurityContext: webapp.Spec.SecurityContext.DeepCopy(),
 						},
-					},
+					}, BuildSidecars(webapp)...),
+					Volumes:         append(append([]corev1.Volume{}, webapp.Spec.Volumes...), storageVolumes...),
+					SecurityContext: webapp.Spec.PodSecurityContext.DeepCopy(),
 				},
 			},
 		},
 	}
+
+	if webapp.Spec.Resources != nil {
+		deploy.Spec.Template.Spec.Containers[0].Resources = *webapp.Spec.Resources.DeepCopy()
+	}
+	deploy.Spec.Template.Spec.ServiceAccountName = ServiceAccountName(webapp)
+	if webapp.Spec.ServiceAccount != nil {
+		deploy.Spec.Template.Spec.AutomountServiceAccountToken = webapp.Spec.ServiceAccount.AutomountServiceAccountToken
+	}
+
+	ApplyScheduling(webapp, &deploy.Spec.Template.Spec)
+	ApplyPolicyDefaults(&deploy.Spec.Template, opts.Policies)
+	if opts.HardenedSecurityDefaults {
+		ApplyHardenedSecurityDefaults(&deploy.Spec.Template.Spec)
+	}
+
+	// template-hash is taken over the whole pod template, so any spec change
+	// (image, sidecars, volumes, ...) rolls the Deployment on the next reconcile.
+	annotations[WebAppTemplateHash] = utils.HashObject(deploy.Spec.Template)
+
+	return deploy
+}
+
+// BuildInitContainers returns the pod init containers. Native sidecars
+// (restartPolicy: Always) come first so that they are already running
+// when the regular init containers (e.g. migrations) start.
+func BuildInitContainers(webapp *webappv1.WebApp) []corev1.Container {
+	var initContainers []corev1.Container
+	for _, sidecar := range webapp.Spec.Sidecars {
+		if isNativeSidecar(sidecar) {
+			initContainers = append(initContainers, *sidecar.DeepCopy())
+		}
+	}
+	for _, container := range webapp.Spec.InitContainers {
+		initContainers = append(initContainers, *container.DeepCopy())
+	}
+	return initContainers
+}
+
+// BuildSidecars returns the sidecars that run as regular pod containers.
+func BuildSidecars(webapp *webappv1.WebApp) []corev1.Container {
+	var sidecars []corev1.Container
+	for _, sidecar := range webapp.Spec.Sidecars {
+		if !isNativeSidecar(sidecar) {
+			sidecars = append(sidecars, *sidecar.DeepCopy())
+		}
+	}
+	return sidecars
+}
+
+func isNativeSidecar(container corev1.Container) bool {
+	return container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways
 }
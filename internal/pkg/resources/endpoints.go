@@ -0,0 +1,122 @@
+package resources
+
+import (
+	"net/url"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+// IngressURL returns the URL the Ingress serves the webapp on. Without a
+// host on the rule the first load balancer address is used, and without
+// either there is nothing to report.
+func IngressURL(ingress *networkingv1.Ingress) string {
+	if len(ingress.Spec.Rules) == 0 {
+		return ""
+	}
+	rule := ingress.Spec.Rules[0]
+	host := rule.Host
+	if host == "" {
+		addresses := IngressAddresses(ingress)
+		if len(addresses) == 0 {
+			return ""
+		}
+		host = addresses[0]
+	}
+	path := ""
+	if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 {
+		path = rule.HTTP.Paths[0].Path
+	}
+
+	scheme := "http"
+	for _, tls := range ingress.Spec.TLS {
+		for _, tlsHost := range tls.Hosts {
+			if tlsHost == rule.Host {
+				scheme = "https"
+			}
+		}
+	}
+	return buildURL(scheme, host, path)
+}
+
+// HTTPRouteURL returns the URL of the first hostname and path of the route.
+// The listener protocol lives on the Gateway, so tls comes from
+// spec.ingress.tls.
+func HTTPRouteURL(route *gatewayv1.HTTPRoute, tls bool) string {
+	if route == nil || len(route.Spec.Hostnames) == 0 {
+		return ""
+	}
+	path := ""
+	if len(route.Spec.Rules) > 0 && len(route.Spec.Rules[0].Matches) > 0 {
+		if match := route.Spec.Rules[0].Matches[0].Path; match != nil && match.Value != nil {
+			path = *match.Value
+		}
+	}
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+	return buildURL(scheme, string(route.Spec.Hostnames[0]), path)
+}
+
+func buildURL(scheme, host, path string) string {
+	if path == "/" {
+		path = ""
+	}
+	return (&url.URL{Scheme: scheme, Host: host, Path: path}).String()
+}
+
+// IngressAddresses returns the load balancer IPs and hostnames of the Ingress.
+func IngressAddresses(ingress *networkingv1.Ingress) []string {
+	var addresses []string
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			addresses = append(addresses, lb.IP)
+		}
+		if lb.Hostname != "" {
+			addresses = append(addresses, lb.Hostname)
+		}
+	}
+	return addresses
+}
+
+// ServiceEndpoints lists the cluster IP, node ports and load balancer
+// addresses of the Service, depending on its type.
+func ServiceEndpoints(svc *corev1.Service) []webappv1.ServiceEndpoint {
+	var endpoints []webappv1.ServiceEndpoint
+	for _, port := range svc.Spec.Ports {
+		if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != corev1.ClusterIPNone {
+			endpoints = append(endpoints, webappv1.ServiceEndpoint{
+				Type:    corev1.ServiceTypeClusterIP,
+				Address: svc.Spec.ClusterIP,
+				Port:    port.Port,
+			})
+		}
+		if port.NodePort != 0 {
+			endpoints = append(endpoints, webappv1.ServiceEndpoint{
+				Type: corev1.ServiceTypeNodePort,
+				Port: port.NodePort,
+			})
+		}
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		for _, lb := range svc.Status.LoadBalancer.Ingress {
+			address := lb.IP
+			if address == "" {
+				address = lb.Hostname
+			}
+			if address == "" {
+				continue
+			}
+			endpoints = append(endpoints, webappv1.ServiceEndpoint{
+				Type:    corev1.ServiceTypeLoadBalancer,
+				Address: address,
+				Port:    port.Port,
+			})
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,87 @@
+package resources
+
+import (
+	"reflect"
+	"testing"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIngressURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		ingress *webappv1.IngressSpec
+		lb      []string
+		want    string
+	}{
+		{name: "host and path", ingress: &webappv1.IngressSpec{Enabled: true, Host: "app.example.com", Path: "/main"}, want: "http://app.example.com/main"},
+		{name: "tls", ingress: &webappv1.IngressSpec{Enabled: true, Host: "app.example.com", TLS: true}, want: "https://app.example.com"},
+		{name: "no host uses load balancer", ingress: &webappv1.IngressSpec{Enabled: true}, lb: []string{"203.0.113.10"}, want: "http://203.0.113.10"},
+		{name: "no host nor address", ingress: &webappv1.IngressSpec{Enabled: true}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webapp := &webappv1.WebApp{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       webappv1.WebAppSpec{Ingress: tt.ingress},
+			}
+			ingress := BuildIngress(webapp, "", nil)
+			for _, ip := range tt.lb {
+				ingress.Status.LoadBalancer.Ingress = append(ingress.Status.LoadBalancer.Ingress, networkingv1.IngressLoadBalancerIngress{IP: ip})
+			}
+			if got := IngressURL(ingress); got != tt.want {
+				t.Errorf("IngressURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPRouteURL(t *testing.T) {
+	webapp := &webappv1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: webappv1.WebAppSpec{
+			Ingress: &webappv1.IngressSpec{Host: "app.example.com", Path: "/api", TLS: true},
+			Routing: &webappv1.RoutingSpec{
+				Type: webappv1.RoutingTypeGateway,
+				Gateway: &webappv1.GatewayRouteSpec{
+					ParentRefs: []webappv1.GatewayParentReference{{Name: "public"}},
+				},
+			},
+		},
+	}
+	if got := HTTPRouteURL(BuildHTTPRoute(webapp), true); got != "https://app.example.com/api" {
+		t.Errorf("HTTPRouteURL() = %q", got)
+	}
+}
+
+func TestServiceEndpoints(t *testing.T) {
+	svc := &corev1.Service{
+		Spec: corev1.ServiceSpec{
+			Type:      corev1.ServiceTypeLoadBalancer,
+			ClusterIP: "10.0.0.5",
+			Ports:     []corev1.ServicePort{{Port: 80, NodePort: 30080}},
+		},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{Hostname: "lb.example.com"}},
+			},
+		},
+	}
+	want := []webappv1.ServiceEndpoint{
+		{Type: corev1.ServiceTypeClusterIP, Address: "10.0.0.5", Port: 80},
+		{Type: corev1.ServiceTypeNodePort, Port: 30080},
+		{Type: corev1.ServiceTypeLoadBalancer, Address: "lb.example.com", Port: 80},
+	}
+	if got := ServiceEndpoints(svc); !reflect.DeepEqual(got, want) {
+		t.Errorf("ServiceEndpoints() = %v, want %v", got, want)
+	}
+
+	// headless services have no cluster IP endpoint
+	svc = &corev1.Service{Spec: corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone, Ports: []corev1.ServicePort{{Port: 80}}}}
+	if got := ServiceEndpoints(svc); got != nil {
+		t.Errorf("headless ServiceEndpoints() = %v", got)
+	}
+}
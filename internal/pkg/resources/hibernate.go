@@ -0,0 +1,45 @@
+package resources
+
+import (
+	"strconv"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/utils/ptr"
+)
+
+// WebAppHibernatedReplicas stores on the Deployment the replica count it had
+// before spec.hibernate scaled it to zero.
+const WebAppHibernatedReplicas = "webapp.crdlego.com/hibernated-replicas"
+
+// ApplyHibernation adjusts the replicas of the desired Deployment. While
+// hibernating the Deployment runs no pods and remembers the replica count of
+// the existing one (found, nil when there is none yet). After hibernation the
+// desired count of spec.replicas or the active schedule applies and the
+// annotation is dropped. A remembered count that differs, e.g. from scaling
+// the Deployment by hand, is not restored: every reconcile after the first
+// would scale it back to spec.replicas right away.
+func ApplyHibernation(webapp *webappv1.WebApp, desired, found *appsv1.Deployment) {
+	if !webapp.Spec.Hibernate {
+		return
+	}
+
+	var stored string
+	var hibernated bool
+	if found != nil {
+		stored, hibernated = found.Annotations[WebAppHibernatedReplicas]
+	}
+
+	if !hibernated {
+		previous := desired.Spec.Replicas
+		if found != nil && found.Spec.Replicas != nil {
+			previous = found.Spec.Replicas
+		}
+		stored = strconv.Itoa(int(ptr.Deref(previous, 1)))
+	}
+	if desired.Annotations == nil {
+		desired.Annotations = map[string]string{}
+	}
+	desired.Annotations[WebAppHibernatedReplicas] = stored
+	desired.Spec.Replicas = ptr.To[int32](0)
+}
@@ -0,0 +1,55 @@
+package resources
+
+import (
+	"testing"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestApplyHibernation(t *testing.T) {
+	webapp := &webappv1.WebApp{Spec: webappv1.WebAppSpec{Replicas: ptr.To[int32](2), Hibernate: true}}
+
+	// a hand-scaled Deployment is hibernated with its current count
+	found := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: ptr.To[int32](5)}}
+	desired := BuildDeployment(webapp, DeploymentOptions{})
+	ApplyHibernation(webapp, desired, found)
+	if *desired.Spec.Replicas != 0 || desired.Annotations[WebAppHibernatedReplicas] != "5" {
+		t.Fatalf("hibernate: replicas=%d annotations=%v", *desired.Spec.Replicas, desired.Annotations)
+	}
+
+	// the stored count survives further reconciles
+	found = &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Annotations: desired.Annotations},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](0)},
+	}
+	desired = BuildDeployment(webapp, DeploymentOptions{})
+	ApplyHibernation(webapp, desired, found)
+	if desired.Annotations[WebAppHibernatedReplicas] != "5" {
+		t.Fatalf("hibernated again: annotations=%v", desired.Annotations)
+	}
+
+	// resuming scales to spec.replicas, which the next reconcile keeps
+	webapp.Spec.Hibernate = false
+	for i := range 2 {
+		desired = BuildDeployment(webapp, DeploymentOptions{})
+		ApplyHibernation(webapp, desired, found)
+		if *desired.Spec.Replicas != 2 {
+			t.Fatalf("resume reconcile %d: replicas=%d", i, *desired.Spec.Replicas)
+		}
+		if _, ok := desired.Annotations[WebAppHibernatedReplicas]; ok {
+			t.Fatalf("resume reconcile %d: annotation not removed", i)
+		}
+		found = desired
+	}
+
+	// a new Deployment created while hibernating remembers spec.replicas
+	webapp.Spec.Hibernate = true
+	desired = BuildDeployment(webapp, DeploymentOptions{})
+	ApplyHibernation(webapp, desired, nil)
+	if desired.Annotations[WebAppHibernatedReplicas] != "2" {
+		t.Fatalf("create: annotations=%v", desired.Annotations)
+	}
+}
@@ -0,0 +1,147 @@
+package resources
+
+import (
+	"sort"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	WebAppSpecHashKey = "webapp.crdlego.com/spec-hash"
+)
+
+// UsesGateway reports whether the webapp is exposed through an HTTPRoute
+// instead of an Ingress.
+func UsesGateway(webapp *webappv1.WebApp) bool {
+	return webapp.Spec.Routing != nil && webapp.Spec.Routing.Type == webappv1.RoutingTypeGateway
+}
+
+// BuildHTTPRoute returns nil unless spec.routing.type is Gateway.
+func BuildHTTPRoute(webapp *webappv1.WebApp) *gatewayv1.HTTPRoute {
+	if !UsesGateway(webapp) || webapp.Spec.Routing.Gateway == nil {
+		return nil
+	}
+	spec := webapp.Spec.Routing.Gateway
+
+	var parentRefs []gatewayv1.ParentReference
+	for _, parent := range spec.ParentRefs {
+		parentRef := gatewayv1.ParentReference{
+			Name: gatewayv1.ObjectName(parent.Name),
+		}
+		if parent.Namespace != "" {
+			parentRef.Namespace = ptr.To(gatewayv1.Namespace(parent.Namespace))
+		}
+		if parent.SectionName != "" {
+			parentRef.SectionName = ptr.To(gatewayv1.SectionName(parent.SectionName))
+		}
+		parentRefs = append(parentRefs, parentRef)
+	}
+
+	hostnames := spec.Hostnames
+	rules := spec.Rules
+	if ingress := webapp.Spec.Ingress; ingress != nil {
+		if len(hostnames) == 0 && ingress.Host != "" {
+			hostnames = []string{ingress.Host}
+		}
+		if len(rules) == 0 {
+			rules = []webappv1.GatewayRouteRule{
+				{Path: ingress.Path, RewritePath: ingress.RewriteTarget},
+			}
+		}
+	}
+	if len(rules) == 0 {
+		rules = []webappv1.GatewayRouteRule{{}}
+	}
+
+	route := &gatewayv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      webapp.Name,
+			Namespace: webapp.Namespace,
+			Labels:    utils.GetCommonLabels(webapp),
+		},
+		Spec: gatewayv1.HTTPRouteSpec{
+			CommonRouteSpec: gatewayv1.CommonRouteSpec{
+				ParentRefs: parentRefs,
+			},
+		},
+	}
+	for _, hostname := range hostnames {
+		route.Spec.Hostnames = append(route.Spec.Hostnames, gatewayv1.Hostname(hostname))
+	}
+	for _, rule := range rules {
+		route.Spec.Rules = append(route.Spec.Rules, buildHTTPRouteRule(webapp, rule))
+	}
+
+	// the API server defaults many HTTPRoute fields, compare by hash instead of DeepEqual
+	route.Annotations = map[string]string{
+		WebAppSpecHashKey: utils.HashObject(route.Spec),
+	}
+
+	return route
+}
+
+func buildHTTPRouteRule(webapp *webappv1.WebApp, rule webappv1.GatewayRouteRule) gatewayv1.HTTPRouteRule {
+	path := rule.Path
+	if path == "" {
+		path = "/"
+	}
+	pathType := gatewayv1.PathMatchPathPrefix
+	if rule.PathType == string(gatewayv1.PathMatchExact) {
+		pathType = gatewayv1.PathMatchExact
+	}
+
+	match := gatewayv1.HTTPRouteMatch{
+		Path: &gatewayv1.HTTPPathMatch{
+			Type:  ptr.To(pathType),
+			Value: ptr.To(path),
+		},
+	}
+	// sort header names so that the generated route is stable across reconciles
+	headerNames := make([]string, 0, len(rule.Headers))
+	for name := range rule.Headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+	for _, name := range headerNames {
+		match.Headers = append(match.Headers, gatewayv1.HTTPHeaderMatch{
+			Type:  ptr.To(gatewayv1.HeaderMatchExact),
+			Name:  gatewayv1.HTTPHeaderName(name),
+			Value: rule.Headers[name],
+		})
+	}
+
+	routeRule := gatewayv1.HTTPRouteRule{
+		Matches: []gatewayv1.HTTPRouteMatch{match},
+		BackendRefs: []gatewayv1.HTTPBackendRef{
+			{
+				BackendRef: gatewayv1.BackendRef{
+					BackendObjectReference: gatewayv1.BackendObjectReference{
+						Name: gatewayv1.ObjectName(webapp.Name),
+						Port: ptr.To(gatewayv1.PortNumber(80)),
+					},
+				},
+			},
+		},
+	}
+
+	// ReplacePrefixMatch is only valid together with a PathPrefix match
+	if rule.RewritePath != "" && pathType == gatewayv1.PathMatchPathPrefix {
+		routeRule.Filters = []gatewayv1.HTTPRouteFilter{
+			{
+				Type: gatewayv1.HTTPRouteFilterURLRewrite,
+				URLRewrite: &gatewayv1.HTTPURLRewriteFilter{
+					Path: &gatewayv1.HTTPPathModifier{
+						Type:               gatewayv1.PrefixMatchHTTPPathModifier,
+						ReplacePrefixMatch: ptr.To(rule.RewritePath),
+					},
+				},
+			},
+		}
+	}
+
+	return routeRule
+}
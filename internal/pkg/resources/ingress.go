@@ -1,6 +1,9 @@
 package resources
 
 import (
+	"sort"
+	"strings"
+
 	webappv1 "github.com/hoon77/crd-operator/api/v1"
 	"github.com/hoon77/crd-operator/internal/pkg/utils"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -9,39 +12,45 @@ import (
 
 const (
 	IngressTLSSecretNameMaSuffix = "-tls"
-	NginxClassName               = "nginx"
-	NginxRewriteTargetAnoKey     = "nginx.ingress.kubernetes.io/rewrite-target"
+	// IngressManagedAnnotationsKey lists the annotation keys set by the operator,
+	// so that annotations added by other tools survive updates.
+	IngressManagedAnnotationsKey = "webapp.crdlego.com/managed-annotations"
 )
 
-func BuildIngress(webapp *webappv1.WebApp) *networkingv1.Ingress {
+// BuildIngress renders the ingress features with the given provider; a nil
+// provider (unknown ingress controller) produces no annotations.
+func BuildIngress(webapp *webappv1.WebApp, className string, provider IngressProvider) *networkingv1.Ingress {
 	if webapp.Spec.Ingress == nil || !webapp.Spec.Ingress.Enabled {
 		return nil
 	}
 
-	path := "/"
-	if webapp.Spec.Ingress.Path != "" {
-		path = webapp.Spec.Ingress.Path
-	}
-
 	port := webapp.Spec.Ingress.Port
 	if port == 0 {
 		port = 80
 	}
 
-	className := webapp.Spec.Ingress.ClassName
-	if className != "" {
-		className = NginxClassName
+	annotations := map[string]string{}
+	if provider != nil {
+		annotations = provider.Annotations(BuildIngressFeatures(webapp))
+	}
+	for key, value := range webapp.Spec.Ingress.Annotations {
+		annotations[key] = value
+	}
+	managedKeys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		managedKeys = append(managedKeys, key)
 	}
+	sort.Strings(managedKeys)
+	annotations[IngressManagedAnnotationsKey] = strings.Join(managedKeys, ",")
 
 	ingress := &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        webapp.Name,
 			Namespace:   webapp.Namespace,
 			Labels:      utils.GetCommonLabels(webapp),
-			Annotations: GetRewriteTargetAnnotations(className, webapp.Spec.Ingress.RewriteTarget),
+			Annotations: annotations,
 		},
 		Spec: networkingv1.IngressSpec{
-			IngressClassName: &className,
 			Rules: []networkingv1.IngressRule{
 				{
 					Host: webapp.Spec.Ingress.Host,
@@ -49,13 +58,13 @@ func BuildIngress(webapp *webappv1.WebApp) *networkingv1.Ingress {
 						HTTP: &networkingv1.HTTPIngressRuleValue{
 							Paths: []networkingv1.HTTPIngressPath{
 								{
-									Path:     path,
+									Path:     ingressPath(webapp),
 									PathType: utils.PtrPathType(networkingv1.PathTypePrefix),
 									Backend: networkingv1.IngressBackend{
 										Service: &networkingv1.IngressServiceBackend{
 											Name: webapp.Name,
 											Port: networkingv1.ServiceBackendPort{
-												Number: webapp.Spec.Ingress.Port,
+												Number: port,
 											},
 										},
 									},
@@ -68,6 +77,11 @@ func BuildIngress(webapp *webappv1.WebApp) *networkingv1.Ingress {
 		},
 	}
 
+	// an empty class name leaves the choice to the cluster default IngressClass
+	if className != "" {
+		ingress.Spec.IngressClassName = &className
+	}
+
 	if webapp.Spec.Ingress.TLS {
 		ingress.Spec.TLS = []networkingv1.IngressTLS{
 			{
@@ -80,17 +94,19 @@ func BuildIngress(webapp *webappv1.WebApp) *networkingv1.Ingress {
 	return ingress
 }
 
-func GetRewriteTargetAnnotations(className string, rewriteTarget string) map[string]string {
-	var annotations map[string]string
-	if rewriteTarget == "" {
-		rewriteTarget = "/"
+// MergeIngressAnnotations applies the desired annotations on top of the
+// existing ones. Keys the operator managed before but no longer renders are
+// removed, every other foreign annotation is kept.
+func MergeIngressAnnotations(existing, desired map[string]string) map[string]string {
+	merged := map[string]string{}
+	for key, value := range existing {
+		merged[key] = value
 	}
-	switch className {
-	case NginxClassName:
-		annotations = map[string]string{
-			NginxRewriteTargetAnoKey: rewriteTarget,
-		}
+	for _, key := range strings.Split(existing[IngressManagedAnnotationsKey], ",") {
+		delete(merged, key)
 	}
-
-	return annotations
+	for key, value := range desired {
+		merged[key] = value
+	}
+	return merged
 }
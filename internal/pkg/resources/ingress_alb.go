@@ -0,0 +1,62 @@
+package resources
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	ALBAnnotationPrefix = "alb.ingress.kubernetes.io/"
+)
+
+// albProvider targets the AWS Load Balancer Controller. An ALB can't rewrite
+// paths, limit body sizes, answer CORS preflights, rate limit without WAF or
+// do basic/external auth (only OIDC and Cognito). Its only timeout is the
+// idle timeout, so connect timeouts are not supported either.
+type albProvider struct{}
+
+func (albProvider) Name() string {
+	return "alb"
+}
+
+func (albProvider) Annotations(features IngressFeatures) map[string]string {
+	annotations := map[string]string{}
+	if features.SSLRedirect {
+		annotations[ALBAnnotationPrefix+"listen-ports"] = `[{"HTTP": 80}, {"HTTPS": 443}]`
+		annotations[ALBAnnotationPrefix+"ssl-redirect"] = "443"
+	}
+	// the ALB only knows the idle timeout
+	if timeout := max(features.ReadTimeoutSeconds, features.SendTimeoutSeconds); timeout > 0 {
+		annotations[ALBAnnotationPrefix+"load-balancer-attributes"] = "idle_timeout.timeout_seconds=" + strconv.Itoa(int(timeout))
+	}
+	if len(features.AllowedSourceRanges) > 0 {
+		annotations[ALBAnnotationPrefix+"inbound-cidrs"] = strings.Join(features.AllowedSourceRanges, ",")
+	}
+	return annotations
+}
+
+func (albProvider) Unsupported(features IngressFeatures) []string {
+	var unsupported []string
+	if features.RewriteTarget != "" {
+		unsupported = append(unsupported, FeatureRewrite)
+	}
+	if features.ConnectTimeoutSeconds > 0 {
+		unsupported = append(unsupported, FeatureTimeouts)
+	}
+	if features.MaxBodySize != "" {
+		unsupported = append(unsupported, FeatureBodySize)
+	}
+	if features.CORS != nil {
+		unsupported = append(unsupported, FeatureCORS)
+	}
+	if features.RateLimitRPS > 0 {
+		unsupported = append(unsupported, FeatureRateLimit)
+	}
+	if features.BasicAuth != nil {
+		unsupported = append(unsupported, FeatureBasicAuth)
+	}
+	if features.ExternalAuth != nil {
+		unsupported = append(unsupported, FeatureExtAuth)
+	}
+	return unsupported
+}
@@ -0,0 +1,75 @@
+package resources
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	HAProxyAnnotationPrefix = "haproxy.org/"
+)
+
+// haproxyProvider targets the HAProxy Technologies kubernetes-ingress controller.
+type haproxyProvider struct{}
+
+func (haproxyProvider) Name() string {
+	return "haproxy"
+}
+
+func (haproxyProvider) Annotations(features IngressFeatures) map[string]string {
+	annotations := map[string]string{}
+	if features.RewriteTarget != "" {
+		// haproxy has no rewrite-target, the prefix is rewritten with a regex
+		prefix := strings.TrimSuffix(features.Path, "/")
+		target := strings.TrimSuffix(features.RewriteTarget, "/")
+		annotations[HAProxyAnnotationPrefix+"path-rewrite"] = fmt.Sprintf(`^%s/?(.*) %s/\1`, prefix, target)
+	}
+	if features.SSLRedirect {
+		annotations[HAProxyAnnotationPrefix+"ssl-redirect"] = "true"
+	}
+	if features.ConnectTimeoutSeconds > 0 {
+		annotations[HAProxyAnnotationPrefix+"timeout-connect"] = strconv.Itoa(int(features.ConnectTimeoutSeconds)) + "s"
+	}
+	// haproxy has a single server timeout for reading and sending
+	if timeout := max(features.ReadTimeoutSeconds, features.SendTimeoutSeconds); timeout > 0 {
+		annotations[HAProxyAnnotationPrefix+"timeout-server"] = strconv.Itoa(int(timeout)) + "s"
+	}
+	if cors := features.CORS; cors != nil {
+		annotations[HAProxyAnnotationPrefix+"cors-enable"] = "true"
+		annotations[HAProxyAnnotationPrefix+"cors-allow-origin"] = joinOrDefault(cors.AllowOrigins, "*")
+		if len(cors.AllowMethods) > 0 {
+			annotations[HAProxyAnnotationPrefix+"cors-allow-methods"] = strings.Join(cors.AllowMethods, ", ")
+		}
+		if len(cors.AllowHeaders) > 0 {
+			annotations[HAProxyAnnotationPrefix+"cors-allow-headers"] = strings.Join(cors.AllowHeaders, ", ")
+		}
+		annotations[HAProxyAnnotationPrefix+"cors-allow-credentials"] = strconv.FormatBool(cors.AllowCredentials)
+	}
+	if features.RateLimitRPS > 0 {
+		annotations[HAProxyAnnotationPrefix+"rate-limit-requests"] = strconv.Itoa(int(features.RateLimitRPS))
+		annotations[HAProxyAnnotationPrefix+"rate-limit-period"] = "1s"
+	}
+	if auth := features.BasicAuth; auth != nil {
+		annotations[HAProxyAnnotationPrefix+"auth-type"] = "basic-auth"
+		annotations[HAProxyAnnotationPrefix+"auth-secret"] = auth.SecretName
+		if auth.Realm != "" {
+			annotations[HAProxyAnnotationPrefix+"auth-realm"] = auth.Realm
+		}
+	}
+	if len(features.AllowedSourceRanges) > 0 {
+		annotations[HAProxyAnnotationPrefix+"allow-list"] = strings.Join(features.AllowedSourceRanges, ",")
+	}
+	return annotations
+}
+
+func (haproxyProvider) Unsupported(features IngressFeatures) []string {
+	var unsupported []string
+	if features.MaxBodySize != "" {
+		unsupported = append(unsupported, FeatureBodySize)
+	}
+	if features.ExternalAuth != nil {
+		unsupported = append(unsupported, FeatureExtAuth)
+	}
+	return unsupported
+}
@@ -0,0 +1,79 @@
+package resources
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	NginxClassName           = "nginx"
+	NginxAnnotationPrefix    = "nginx.ingress.kubernetes.io/"
+	NginxRewriteTargetAnoKey = NginxAnnotationPrefix + "rewrite-target"
+)
+
+// nginxProvider supports every feature through ingress-nginx annotations.
+type nginxProvider struct{}
+
+func (nginxProvider) Name() string {
+	return NginxClassName
+}
+
+func (nginxProvider) Annotations(features IngressFeatures) map[string]string {
+	annotations := map[string]string{}
+	if features.RewriteTarget != "" {
+		annotations[NginxRewriteTargetAnoKey] = features.RewriteTarget
+	}
+	if features.SSLRedirect {
+		annotations[NginxAnnotationPrefix+"force-ssl-redirect"] = "true"
+	}
+	if features.ConnectTimeoutSeconds > 0 {
+		annotations[NginxAnnotationPrefix+"proxy-connect-timeout"] = strconv.Itoa(int(features.ConnectTimeoutSeconds))
+	}
+	if features.ReadTimeoutSeconds > 0 {
+		annotations[NginxAnnotationPrefix+"proxy-read-timeout"] = strconv.Itoa(int(features.ReadTimeoutSeconds))
+	}
+	if features.SendTimeoutSeconds > 0 {
+		annotations[NginxAnnotationPrefix+"proxy-send-timeout"] = strconv.Itoa(int(features.SendTimeoutSeconds))
+	}
+	if features.MaxBodySize != "" {
+		annotations[NginxAnnotationPrefix+"proxy-body-size"] = features.MaxBodySize
+	}
+	if cors := features.CORS; cors != nil {
+		annotations[NginxAnnotationPrefix+"enable-cors"] = "true"
+		annotations[NginxAnnotationPrefix+"cors-allow-origin"] = joinOrDefault(cors.AllowOrigins, "*")
+		if len(cors.AllowMethods) > 0 {
+			annotations[NginxAnnotationPrefix+"cors-allow-methods"] = strings.Join(cors.AllowMethods, ", ")
+		}
+		if len(cors.AllowHeaders) > 0 {
+			annotations[NginxAnnotationPrefix+"cors-allow-headers"] = strings.Join(cors.AllowHeaders, ", ")
+		}
+		annotations[NginxAnnotationPrefix+"cors-allow-credentials"] = strconv.FormatBool(cors.AllowCredentials)
+	}
+	if features.RateLimitRPS > 0 {
+		annotations[NginxAnnotationPrefix+"limit-rps"] = strconv.Itoa(int(features.RateLimitRPS))
+	}
+	if auth := features.BasicAuth; auth != nil {
+		annotations[NginxAnnotationPrefix+"auth-type"] = "basic"
+		annotations[NginxAnnotationPrefix+"auth-secret"] = auth.SecretName
+		if auth.Realm != "" {
+			annotations[NginxAnnotationPrefix+"auth-realm"] = auth.Realm
+		}
+	}
+	if auth := features.ExternalAuth; auth != nil {
+		annotations[NginxAnnotationPrefix+"auth-url"] = auth.URL
+		if auth.SignInURL != "" {
+			annotations[NginxAnnotationPrefix+"auth-signin"] = auth.SignInURL
+		}
+		if len(auth.ResponseHeaders) > 0 {
+			annotations[NginxAnnotationPrefix+"auth-response-headers"] = strings.Join(auth.ResponseHeaders, ",")
+		}
+	}
+	if len(features.AllowedSourceRanges) > 0 {
+		annotations[NginxAnnotationPrefix+"whitelist-source-range"] = strings.Join(features.AllowedSourceRanges, ",")
+	}
+	return annotations
+}
+
+func (nginxProvider) Unsupported(IngressFeatures) []string {
+	return nil
+}
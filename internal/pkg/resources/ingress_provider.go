@@ -0,0 +1,147 @@
+package resources
+
+import (
+	"strings"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// IngressFeatures are the controller independent HTTP features of a WebApp
+// ingress. An IngressProvider translates them into the annotations understood
+// by one ingress controller.
+type IngressFeatures struct {
+	Path          string
+	RewriteTarget string
+	SSLRedirect   bool
+
+	ConnectTimeoutSeconds int32
+	ReadTimeoutSeconds    int32
+	SendTimeoutSeconds    int32
+	MaxBodySize           string
+
+	CORS *CORSFeature
+
+	// RateLimitRPS limits the requests per second from a single client IP.
+	RateLimitRPS int32
+
+	BasicAuth    *webappv1.IngressBasicAuth
+	ExternalAuth *webappv1.IngressExternalAuth
+
+	AllowedSourceRanges []string
+}
+
+type CORSFeature struct {
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	AllowCredentials bool
+}
+
+// Feature names reported by IngressProvider.Unsupported.
+const (
+	FeatureRewrite     = "rewrite"
+	FeatureSSLRedirect = "sslRedirect"
+	FeatureTimeouts    = "timeouts"
+	FeatureBodySize    = "maxBodySize"
+	FeatureCORS        = "cors"
+	FeatureRateLimit   = "rateLimit"
+	FeatureBasicAuth   = "basicAuth"
+	FeatureExtAuth     = "externalAuth"
+	FeatureAllowlist   = "allowedSourceRanges"
+)
+
+// IngressProvider renders IngressFeatures as ingress controller annotations.
+type IngressProvider interface {
+	// Name is the short provider name, e.g. "nginx".
+	Name() string
+	// Annotations returns the annotations for the supported features.
+	Annotations(features IngressFeatures) map[string]string
+	// Unsupported lists the requested features the controller can't express
+	// with annotations.
+	Unsupported(features IngressFeatures) []string
+}
+
+// ingressProviders maps IngressClass spec.controller values to providers.
+var ingressProviders = map[string]IngressProvider{
+	"k8s.io/ingress-nginx":                   nginxProvider{},
+	"traefik.io/ingress-controller":          traefikProvider{},
+	"haproxy.org/ingress-controller/haproxy": haproxyProvider{},
+	"haproxy-ingress.github.io/controller":   haproxyProvider{},
+	"ingress.k8s.aws/alb":                    albProvider{},
+}
+
+// IngressProviderForClass selects the provider from the IngressClass controller
+// name. When the IngressClass can't be read, the class name itself is matched
+// against the provider names so that "nginx" keeps working.
+func IngressProviderForClass(ingressClass *networkingv1.IngressClass, className string) IngressProvider {
+	if ingressClass != nil {
+		if provider, ok := ingressProviders[ingressClass.Spec.Controller]; ok {
+			return provider
+		}
+		for controller, provider := range ingressProviders {
+			if strings.HasPrefix(ingressClass.Spec.Controller, controller) {
+				return provider
+			}
+		}
+		return nil
+	}
+
+	for _, provider := range ingressProviders {
+		if provider.Name() == className {
+			return provider
+		}
+	}
+	return nil
+}
+
+// BuildIngressFeatures collects the ingress features requested by the WebApp.
+func BuildIngressFeatures(webapp *webappv1.WebApp) IngressFeatures {
+	ingress := webapp.Spec.Ingress
+	features := IngressFeatures{
+		Path:                ingressPath(webapp),
+		RewriteTarget:       ingress.RewriteTarget,
+		SSLRedirect:         ingress.SSLRedirect,
+		MaxBodySize:         ingress.MaxBodySize,
+		AllowedSourceRanges: ingress.AllowedSourceRanges,
+	}
+	// a sub path is rewritten to "/" by default, the app is served from its root
+	if features.RewriteTarget == "" && features.Path != "/" {
+		features.RewriteTarget = "/"
+	}
+	if timeouts := ingress.Timeouts; timeouts != nil {
+		features.ConnectTimeoutSeconds = timeouts.ConnectSeconds
+		features.ReadTimeoutSeconds = timeouts.ReadSeconds
+		features.SendTimeoutSeconds = timeouts.SendSeconds
+	}
+	if cors := ingress.CORS; cors != nil {
+		features.CORS = &CORSFeature{
+			AllowOrigins:     cors.AllowOrigins,
+			AllowMethods:     cors.AllowMethods,
+			AllowHeaders:     cors.AllowHeaders,
+			AllowCredentials: cors.AllowCredentials,
+		}
+	}
+	if ingress.RateLimit != nil {
+		features.RateLimitRPS = ingress.RateLimit.RequestsPerSecond
+	}
+	if auth := ingress.Auth; auth != nil {
+		features.BasicAuth = auth.Basic
+		features.ExternalAuth = auth.External
+	}
+	return features
+}
+
+func ingressPath(webapp *webappv1.WebApp) string {
+	if webapp.Spec.Ingress == nil || webapp.Spec.Ingress.Path == "" {
+		return "/"
+	}
+	return webapp.Spec.Ingress.Path
+}
+
+func joinOrDefault(values []string, defaultValue string) string {
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,194 @@
+package resources
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIngressProviderForClass(t *testing.T) {
+	tests := []struct {
+		controller string
+		className  string
+		want       string
+	}{
+		{controller: "k8s.io/ingress-nginx", want: "nginx"},
+		{controller: "traefik.io/ingress-controller", want: "traefik"},
+		{controller: "haproxy.org/ingress-controller/haproxy-internal", want: "haproxy"},
+		{controller: "ingress.k8s.aws/alb", want: "alb"},
+		{controller: "example.com/unknown", want: ""},
+		{className: "nginx", want: "nginx"},
+		{className: "public", want: ""},
+	}
+
+	for _, tt := range tests {
+		var ingressClass *networkingv1.IngressClass
+		if tt.controller != "" {
+			ingressClass = &networkingv1.IngressClass{Spec: networkingv1.IngressClassSpec{Controller: tt.controller}}
+		}
+		provider := IngressProviderForClass(ingressClass, tt.className)
+		got := ""
+		if provider != nil {
+			got = provider.Name()
+		}
+		if got != tt.want {
+			t.Errorf("IngressProviderForClass(%q, %q) = %q, want %q", tt.controller, tt.className, got, tt.want)
+		}
+	}
+}
+
+func TestIngressProviderAnnotations(t *testing.T) {
+	features := IngressFeatures{
+		Path:               "/main",
+		RewriteTarget:      "/",
+		SSLRedirect:        true,
+		ReadTimeoutSeconds: 60,
+		MaxBodySize:        "10m",
+		CORS:               &CORSFeature{AllowOrigins: []string{"https://example.com"}},
+		RateLimitRPS:       10,
+	}
+
+	tests := []struct {
+		provider        IngressProvider
+		wantAnnotations map[string]string
+		wantUnsupported []string
+	}{
+		{
+			provider: nginxProvider{},
+			wantAnnotations: map[string]string{
+				NginxRewriteTargetAnoKey:                         "/",
+				NginxAnnotationPrefix + "force-ssl-redirect":     "true",
+				NginxAnnotationPrefix + "proxy-read-timeout":     "60",
+				NginxAnnotationPrefix + "proxy-body-size":        "10m",
+				NginxAnnotationPrefix + "enable-cors":            "true",
+				NginxAnnotationPrefix + "cors-allow-origin":      "https://example.com",
+				NginxAnnotationPrefix + "cors-allow-credentials": "false",
+				NginxAnnotationPrefix + "limit-rps":              "10",
+			},
+		},
+		{
+			// an entrypoint annotation would stop serving HTTP, not redirect it
+			provider:        traefikProvider{},
+			wantAnnotations: map[string]string{},
+			wantUnsupported: []string{FeatureRewrite, FeatureSSLRedirect, FeatureTimeouts, FeatureBodySize, FeatureCORS, FeatureRateLimit},
+		},
+		{
+			provider: haproxyProvider{},
+			wantAnnotations: map[string]string{
+				HAProxyAnnotationPrefix + "path-rewrite":           `^/main/?(.*) /\1`,
+				HAProxyAnnotationPrefix + "ssl-redirect":           "true",
+				HAProxyAnnotationPrefix + "timeout-server":         "60s",
+				HAProxyAnnotationPrefix + "cors-enable":            "true",
+				HAProxyAnnotationPrefix + "cors-allow-origin":      "https://example.com",
+				HAProxyAnnotationPrefix + "cors-allow-credentials": "false",
+				HAProxyAnnotationPrefix + "rate-limit-requests":    "10",
+				HAProxyAnnotationPrefix + "rate-limit-period":      "1s",
+			},
+			wantUnsupported: []string{FeatureBodySize},
+		},
+		{
+			provider: albProvider{},
+			wantAnnotations: map[string]string{
+				ALBAnnotationPrefix + "listen-ports":             `[{"HTTP": 80}, {"HTTPS": 443}]`,
+				ALBAnnotationPrefix + "ssl-redirect":             "443",
+				ALBAnnotationPrefix + "load-balancer-attributes": "idle_timeout.timeout_seconds=60",
+			},
+			wantUnsupported: []string{FeatureRewrite, FeatureBodySize, FeatureCORS, FeatureRateLimit},
+		},
+	}
+
+	for _, tt := range tests {
+		annotations := tt.provider.Annotations(features)
+		if len(annotations) != len(tt.wantAnnotations) {
+			t.Errorf("%s: got %d annotations %v, want %d", tt.provider.Name(), len(annotations), annotations, len(tt.wantAnnotations))
+		}
+		for key, want := range tt.wantAnnotations {
+			if annotations[key] != want {
+				t.Errorf("%s: annotation %s = %q, want %q", tt.provider.Name(), key, annotations[key], want)
+			}
+		}
+
+		unsupported := tt.provider.Unsupported(features)
+		if len(unsupported) != len(tt.wantUnsupported) {
+			t.Errorf("%s: unsupported = %v, want %v", tt.provider.Name(), unsupported, tt.wantUnsupported)
+			continue
+		}
+		for i := range unsupported {
+			if unsupported[i] != tt.wantUnsupported[i] {
+				t.Errorf("%s: unsupported = %v, want %v", tt.provider.Name(), unsupported, tt.wantUnsupported)
+			}
+		}
+	}
+}
+
+func TestBuildIngressClassName(t *testing.T) {
+	webapp := &webappv1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: webappv1.WebAppSpec{
+			Ingress: &webappv1.IngressSpec{Enabled: true, Path: "/main"},
+		},
+	}
+
+	ingress := BuildIngress(webapp, "", nginxProvider{})
+	if ingress.Spec.IngressClassName != nil {
+		t.Errorf("IngressClassName = %q, want nil for the cluster default class", *ingress.Spec.IngressClassName)
+	}
+	if ingress.Annotations[NginxRewriteTargetAnoKey] != "/" {
+		t.Errorf("rewrite-target = %q, want /", ingress.Annotations[NginxRewriteTargetAnoKey])
+	}
+	if port := ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Port.Number; port != 80 {
+		t.Errorf("backend port = %d, want 80", port)
+	}
+
+	ingress = BuildIngress(webapp, "traefik-public", traefikProvider{})
+	if *ingress.Spec.IngressClassName != "traefik-public" {
+		t.Errorf("IngressClassName = %q, want traefik-public", *ingress.Spec.IngressClassName)
+	}
+}
+
+func TestMergeIngressAnnotations(t *testing.T) {
+	webapp := &webappv1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: webappv1.WebAppSpec{
+			Ingress: &webappv1.IngressSpec{
+				Enabled:     true,
+				SSLRedirect: true,
+				Annotations: map[string]string{"example.com/team": "web"},
+			},
+		},
+	}
+	first := BuildIngress(webapp, "nginx", nginxProvider{}).Annotations
+
+	// another tool adds an annotation between two reconciles
+	existing := MergeIngressAnnotations(nil, first)
+	existing["cert-manager.io/cluster-issuer"] = "letsencrypt"
+
+	webapp.Spec.Ingress.SSLRedirect = false
+	desired := BuildIngress(webapp, "nginx", nginxProvider{}).Annotations
+	merged := MergeIngressAnnotations(existing, desired)
+
+	if merged["cert-manager.io/cluster-issuer"] != "letsencrypt" {
+		t.Errorf("foreign annotation was removed: %v", merged)
+	}
+	if merged["example.com/team"] != "web" {
+		t.Errorf("pass-through annotation is missing: %v", merged)
+	}
+	if _, ok := merged[NginxAnnotationPrefix+"force-ssl-redirect"]; ok {
+		t.Errorf("stale managed annotation was kept: %v", merged)
+	}
+}
+
+func TestALBConnectTimeout(t *testing.T) {
+	features := IngressFeatures{ConnectTimeoutSeconds: 5, ReadTimeoutSeconds: 60}
+	want := map[string]string{ALBAnnotationPrefix + "load-balancer-attributes": "idle_timeout.timeout_seconds=60"}
+	if got := (albProvider{}).Annotations(features); !reflect.DeepEqual(got, want) {
+		t.Errorf("annotations = %v, want %v", got, want)
+	}
+	if got := (albProvider{}).Unsupported(features); !slices.Equal(got, []string{FeatureTimeouts}) {
+		t.Errorf("unsupported = %v, want [%s]", got, FeatureTimeouts)
+	}
+}
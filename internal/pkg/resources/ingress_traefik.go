@@ -0,0 +1,52 @@
+package resources
+
+const (
+	TraefikAnnotationPrefix = "traefik.ingress.kubernetes.io/"
+)
+
+// traefikProvider only supports what the Traefik Kubernetes Ingress provider
+// exposes as annotations, which is nothing this operator renders today.
+// Rewrites, HTTPS redirects, CORS, timeouts, body size, rate limits, auth and
+// allowlists need Traefik Middleware objects. Moving the router to the
+// websecure entrypoint would drop plain HTTP instead of redirecting it.
+type traefikProvider struct{}
+
+func (traefikProvider) Name() string {
+	return "traefik"
+}
+
+func (traefikProvider) Annotations(IngressFeatures) map[string]string {
+	return map[string]string{}
+}
+
+func (traefikProvider) Unsupported(features IngressFeatures) []string {
+	var unsupported []string
+	if features.RewriteTarget != "" {
+		unsupported = append(unsupported, FeatureRewrite)
+	}
+	if features.SSLRedirect {
+		unsupported = append(unsupported, FeatureSSLRedirect)
+	}
+	if features.ConnectTimeoutSeconds > 0 || features.ReadTimeoutSeconds > 0 || features.SendTimeoutSeconds > 0 {
+		unsupported = append(unsupported, FeatureTimeouts)
+	}
+	if features.MaxBodySize != "" {
+		unsupported = append(unsupported, FeatureBodySize)
+	}
+	if features.CORS != nil {
+		unsupported = append(unsupported, FeatureCORS)
+	}
+	if features.RateLimitRPS > 0 {
+		unsupported = append(unsupported, FeatureRateLimit)
+	}
+	if features.BasicAuth != nil {
+		unsupported = append(unsupported, FeatureBasicAuth)
+	}
+	if features.ExternalAuth != nil {
+		unsupported = append(unsupported, FeatureExtAuth)
+	}
+	if len(features.AllowedSourceRanges) > 0 {
+		unsupported = append(unsupported, FeatureAllowlist)
+	}
+	return unsupported
+}
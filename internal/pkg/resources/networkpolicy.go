@@ -0,0 +1,141 @@
+package resources
+
+import (
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	DefaultIngressControllerNamespace = "ingress-nginx"
+	NamespaceNameLabel                = "kubernetes.io/metadata.name"
+)
+
+// BuildNetworkPolicy returns nil unless spec.networkPolicy.enabled is set.
+func BuildNetworkPolicy(webapp *webappv1.WebApp) *networkingv1.NetworkPolicy {
+	spec := webapp.Spec.NetworkPolicy
+	if spec == nil || !spec.Enabled {
+		return nil
+	}
+
+	ingressNamespace := spec.IngressControllerNamespace
+	if ingressNamespace == "" {
+		ingressNamespace = DefaultIngressControllerNamespace
+	}
+
+	from := []networkingv1.NetworkPolicyPeer{namespacePeer(ingressNamespace)}
+	for _, namespace := range spec.AllowFromNamespaces {
+		from = append(from, namespacePeer(namespace))
+	}
+	from = append(from, spec.AllowFrom...)
+
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      webapp.Name,
+			Namespace: webapp.Namespace,
+			Labels:    utils.GetCommonLabels(webapp),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: utils.GetCommonLabels(webapp),
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From:  from,
+					Ports: ingressPorts(webapp),
+				},
+			},
+		},
+	}
+
+	if spec.DefaultDeny || len(spec.Egress) > 0 {
+		policy.Spec.PolicyTypes = append(policy.Spec.PolicyTypes, networkingv1.PolicyTypeEgress)
+		policy.Spec.Egress = append([]networkingv1.NetworkPolicyEgressRule{dnsEgressRule()}, buildEgressRules(webapp)...)
+	}
+
+	return policy
+}
+
+// ingressPorts allows the port of the webapp container and the ports the
+// sidecars declare, e.g. a proxy terminating the traffic in front of it.
+func ingressPorts(webapp *webappv1.WebApp) []networkingv1.NetworkPolicyPort {
+	ports := []networkingv1.NetworkPolicyPort{
+		{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(WebAppContainerPort))},
+	}
+	for _, sidecar := range webapp.Spec.Sidecars {
+		for _, port := range sidecar.Ports {
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = corev1.ProtocolTCP
+			}
+			ports = append(ports, networkingv1.NetworkPolicyPort{
+				Protocol: ptr.To(protocol),
+				Port:     ptr.To(intstr.FromInt32(port.ContainerPort)),
+			})
+		}
+	}
+	return ports
+}
+
+func buildEgressRules(webapp *webappv1.WebApp) []networkingv1.NetworkPolicyEgressRule {
+	var rules []networkingv1.NetworkPolicyEgressRule
+	for _, egress := range webapp.Spec.NetworkPolicy.Egress {
+		var to []networkingv1.NetworkPolicyPeer
+		for _, cidr := range egress.CIDRs {
+			to = append(to, networkingv1.NetworkPolicyPeer{
+				IPBlock: &networkingv1.IPBlock{CIDR: cidr},
+			})
+		}
+		for _, ref := range egress.WebApps {
+			target := &webappv1.WebApp{ObjectMeta: metav1.ObjectMeta{Name: ref.Name, Namespace: ref.Namespace}}
+			if target.Namespace == "" {
+				target.Namespace = webapp.Namespace
+			}
+			to = append(to, networkingv1.NetworkPolicyPeer{
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{NamespaceNameLabel: target.Namespace},
+				},
+				PodSelector: &metav1.LabelSelector{
+					MatchLabels: utils.GetCommonLabels(target),
+				},
+			})
+		}
+		// default the protocol like the API server does, so that the
+		// generated spec compares equal to the stored one
+		var ports []networkingv1.NetworkPolicyPort
+		for _, port := range egress.Ports {
+			if port.Protocol == nil {
+				port.Protocol = ptr.To(corev1.ProtocolTCP)
+			}
+			ports = append(ports, port)
+		}
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+			To:    to,
+			Ports: ports,
+		})
+	}
+	return rules
+}
+
+func dnsEgressRule() networkingv1.NetworkPolicyEgressRule {
+	return networkingv1.NetworkPolicyEgressRule{
+		To: []networkingv1.NetworkPolicyPeer{namespacePeer("kube-system")},
+		Ports: []networkingv1.NetworkPolicyPort{
+			{Protocol: ptr.To(corev1.ProtocolUDP), Port: ptr.To(intstr.FromInt32(53))},
+			{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(53))},
+		},
+	}
+}
+
+func namespacePeer(namespace string) networkingv1.NetworkPolicyPeer {
+	return networkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{NamespaceNameLabel: namespace},
+		},
+	}
+}
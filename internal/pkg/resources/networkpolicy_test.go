@@ -0,0 +1,51 @@
+package resources
+
+import (
+	"reflect"
+	"testing"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+func TestBuildNetworkPolicy(t *testing.T) {
+	webapp := &webappv1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: webappv1.WebAppSpec{
+			Sidecars: []corev1.Container{
+				{Name: "proxy", Ports: []corev1.ContainerPort{{ContainerPort: 8443}, {ContainerPort: 9000, Protocol: corev1.ProtocolUDP}}},
+			},
+			NetworkPolicy: &webappv1.NetworkPolicySpec{
+				Enabled: true,
+				Egress: []webappv1.NetworkPolicyEgressRule{
+					{WebApps: []webappv1.WebAppReference{{Name: "api"}, {Name: "auth", Namespace: "identity"}}},
+				},
+			},
+		},
+	}
+	policy := BuildNetworkPolicy(webapp)
+
+	wantPorts := []networkingv1.NetworkPolicyPort{
+		{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(WebAppContainerPort))},
+		{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(8443))},
+		{Protocol: ptr.To(corev1.ProtocolUDP), Port: ptr.To(intstr.FromInt32(9000))},
+	}
+	if got := policy.Spec.Ingress[0].Ports; !reflect.DeepEqual(got, wantPorts) {
+		t.Errorf("ingress ports = %v, want %v", got, wantPorts)
+	}
+
+	// the first egress rule allows DNS
+	to := policy.Spec.Egress[1].To
+	for i, want := range []struct{ namespace, app string }{{"shop", "api"}, {"identity", "auth"}} {
+		if got := to[i].NamespaceSelector.MatchLabels[NamespaceNameLabel]; got != want.namespace {
+			t.Errorf("egress peer %d namespace = %q, want %q", i, got, want.namespace)
+		}
+		if got := to[i].PodSelector.MatchLabels["app"]; got != want.app {
+			t.Errorf("egress peer %d app = %q, want %q", i, got, want.app)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package resources
+
+import (
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/utils"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// BuildPodDisruptionBudget returns nil when the WebApp should not have a
+// PodDisruptionBudget: a single replica can't be protected without blocking
+// node drains forever.
+func BuildPodDisruptionBudget(webapp *webappv1.WebApp) *policyv1.PodDisruptionBudget {
+	if webapp.Spec.Replicas == nil || *webapp.Spec.Replicas <= 1 {
+		return nil
+	}
+
+	spec := webapp.Spec.PodDisruptionBudget
+	if spec != nil && spec.Enabled != nil && !*spec.Enabled {
+		return nil
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      webapp.Name,
+			Namespace: webapp.Namespace,
+			Labels:    utils.GetCommonLabels(webapp),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: utils.GetCommonLabels(webapp),
+			},
+		},
+	}
+
+	switch {
+	case spec != nil && spec.MinAvailable != nil:
+		pdb.Spec.MinAvailable = spec.MinAvailable
+	case spec != nil && spec.MaxUnavailable != nil:
+		pdb.Spec.MaxUnavailable = spec.MaxUnavailable
+	default:
+		maxUnavailable := intstr.FromInt32(1)
+		pdb.Spec.MaxUnavailable = &maxUnavailable
+	}
+
+	return pdb
+}
@@ -0,0 +1,178 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ListPolicies returns the WebAppPolicies selecting the namespace, sorted by name.
+func ListPolicies(ctx context.Context, c client.Reader, namespace string) ([]webappv1.WebAppPolicy, error) {
+	var policies webappv1.WebAppPolicyList
+	if err := c.List(ctx, &policies); err != nil {
+		return nil, err
+	}
+	if len(policies.Items) == 0 {
+		return nil, nil
+	}
+	var ns corev1.Namespace
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+		return nil, err
+	}
+	return MatchingPolicies(policies.Items, &ns)
+}
+
+// MatchingPolicies returns the policies selecting the namespace, sorted by name.
+func MatchingPolicies(policies []webappv1.WebAppPolicy, namespace *corev1.Namespace) ([]webappv1.WebAppPolicy, error) {
+	var matching []webappv1.WebAppPolicy
+	for _, policy := range policies {
+		selector := labels.Everything()
+		if policy.Spec.NamespaceSelector != nil {
+			var err error
+			if selector, err = metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector); err != nil {
+				return nil, fmt.Errorf("WebAppPolicy %s: %w", policy.Name, err)
+			}
+		}
+		if selector.Matches(labels.Set(namespace.Labels)) {
+			matching = append(matching, policy)
+		}
+	}
+	slices.SortFunc(matching, func(a, b webappv1.WebAppPolicy) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return matching, nil
+}
+
+// PolicyViolations lists the limits of the policies the webapp violates.
+// Images are matched against the allowed registries like the operator-wide
+// --allowed-registries, see registry.DisallowedImages.
+func PolicyViolations(webapp *webappv1.WebApp, policies []webappv1.WebAppPolicy) []string {
+	var violations []string
+	for _, policy := range policies {
+		limits := policy.Spec.Limits
+		violate := func(format string, args ...any) {
+			violations = append(violations, fmt.Sprintf("WebAppPolicy %s: ", policy.Name)+fmt.Sprintf(format, args...))
+		}
+
+		if len(limits.AllowedRegistries) > 0 {
+			for _, image := range registry.DisallowedImages(WebAppImages(webapp), limits.AllowedRegistries) {
+				violate("image %s is not from an allowed registry", image)
+			}
+		}
+
+		if limits.MaxReplicas != nil {
+			if replicas := webapp.Spec.Replicas; replicas != nil && *replicas > *limits.MaxReplicas {
+				violate("replicas %d exceed the maximum of %d", *replicas, *limits.MaxReplicas)
+			}
+			for _, schedule := range webapp.Spec.Schedules {
+				if schedule.Replicas != nil && *schedule.Replicas > *limits.MaxReplicas {
+					violate("schedule %s replicas %d exceed the maximum of %d", schedule.Name, *schedule.Replicas, *limits.MaxReplicas)
+				}
+			}
+		}
+
+		for _, name := range limits.RequiredResources {
+			if webapp.Spec.Resources == nil {
+				violate("resources.requests.%s is required", name)
+				continue
+			}
+			if _, ok := webapp.Spec.Resources.Requests[name]; !ok {
+				violate("resources.requests.%s is required", name)
+			}
+		}
+
+		if len(limits.AllowedIngressDomains) > 0 {
+			for _, host := range webAppHosts(webapp) {
+				if !slices.ContainsFunc(limits.AllowedIngressDomains, func(domain string) bool {
+					return hostInDomain(host, domain)
+				}) {
+					violate("host %s is not in an allowed domain", host)
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// ApplyPolicyDefaults merges the policy defaults into the pod template. Values
+// already set win, so the WebApp and then the first policy by name take
+// precedence.
+func ApplyPolicyDefaults(template *corev1.PodTemplateSpec, policies []webappv1.WebAppPolicy) {
+	for _, policy := range policies {
+		defaults := policy.Spec.Defaults
+		if len(defaults.Labels) > 0 {
+			// the template labels are shared with the Deployment selector
+			podLabels := maps.Clone(template.Labels)
+			if podLabels == nil {
+				podLabels = map[string]string{}
+			}
+			for key, value := range defaults.Labels {
+				if _, ok := podLabels[key]; !ok {
+					podLabels[key] = value
+				}
+			}
+			template.Labels = podLabels
+		}
+		for _, toleration := range defaults.Tolerations {
+			if !slices.ContainsFunc(template.Spec.Tolerations, func(t corev1.Toleration) bool {
+				return equality.Semantic.DeepEqual(t, toleration)
+			}) {
+				template.Spec.Tolerations = append(template.Spec.Tolerations, toleration)
+			}
+		}
+		for i := range template.Spec.Containers {
+			container := &template.Spec.Containers[i]
+			if container.Name != WebAppContainerName {
+				continue
+			}
+			if container.LivenessProbe == nil && defaults.LivenessProbe != nil {
+				container.LivenessProbe = defaults.LivenessProbe.DeepCopy()
+			}
+			if container.ReadinessProbe == nil && defaults.ReadinessProbe != nil {
+				container.ReadinessProbe = defaults.ReadinessProbe.DeepCopy()
+			}
+		}
+	}
+}
+
+// WebAppImages returns the images of the webapp, init and sidecar containers.
+func WebAppImages(webapp *webappv1.WebApp) []string {
+	images := []string{webapp.Spec.Image}
+	for _, container := range webapp.Spec.InitContainers {
+		images = append(images, container.Image)
+	}
+	for _, container := range webapp.Spec.Sidecars {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+func webAppHosts(webapp *webappv1.WebApp) []string {
+	var hosts []string
+	if webapp.Spec.Ingress != nil && webapp.Spec.Ingress.Host != "" {
+		hosts = append(hosts, webapp.Spec.Ingress.Host)
+	}
+	if UsesGateway(webapp) && webapp.Spec.Routing.Gateway != nil {
+		hosts = append(hosts, webapp.Spec.Routing.Gateway.Hostnames...)
+	}
+	return hosts
+}
+
+// hostInDomain reports whether host, possibly a wildcard, is the domain or
+// one of its subdomains.
+func hostInDomain(host, domain string) bool {
+	host = strings.TrimPrefix(host, "*.")
+	domain = strings.TrimPrefix(domain, ".")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,128 @@
+package resources
+
+import (
+	"slices"
+	"testing"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestMatchingPolicies(t *testing.T) {
+	policies := []webappv1.WebAppPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "payments"}, Spec: webappv1.WebAppPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "baseline"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "search"}, Spec: webappv1.WebAppPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "search"}},
+		}},
+	}
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "payments"}}}
+	matching, err := MatchingPolicies(policies, namespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, policy := range matching {
+		names = append(names, policy.Name)
+	}
+	if want := []string{"baseline", "payments"}; !slices.Equal(names, want) {
+		t.Errorf("MatchingPolicies() = %v, want %v", names, want)
+	}
+}
+
+func TestPolicyViolations(t *testing.T) {
+	policy := webappv1.WebAppPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+		Spec: webappv1.WebAppPolicySpec{Limits: webappv1.WebAppPolicyLimits{
+			AllowedRegistries:     []string{"registry.example.com/"},
+			MaxReplicas:           ptr.To[int32](3),
+			RequiredResources:     []corev1.ResourceName{corev1.ResourceCPU},
+			AllowedIngressDomains: []string{"example.com"},
+		}},
+	}
+	compliant := webappv1.WebAppSpec{
+		Image:     "registry.example.com/web:1.0",
+		Replicas:  ptr.To[int32](3),
+		Resources: &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+		Ingress:   &webappv1.IngressSpec{Enabled: true, Host: "*.app.example.com"},
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*webappv1.WebAppSpec)
+		want   []string
+	}{
+		{name: "compliant", mutate: func(*webappv1.WebAppSpec) {}},
+		{
+			name:   "registry",
+			mutate: func(spec *webappv1.WebAppSpec) { spec.Sidecars = []corev1.Container{{Name: "proxy", Image: "envoy"}} },
+			want:   []string{"WebAppPolicy payments: image envoy is not from an allowed registry"},
+		},
+		{
+			name:   "registry host prefix",
+			mutate: func(spec *webappv1.WebAppSpec) { spec.Image = "registry.example.com.evil.com/web:1.0" },
+			want:   []string{"WebAppPolicy payments: image registry.example.com.evil.com/web:1.0 is not from an allowed registry"},
+		},
+		{
+			name: "replicas",
+			mutate: func(spec *webappv1.WebAppSpec) {
+				spec.Schedules = []webappv1.ScheduleSpec{{Name: "peak", Replicas: ptr.To[int32](5)}}
+			},
+			want: []string{"WebAppPolicy payments: schedule peak replicas 5 exceed the maximum of 3"},
+		},
+		{
+			name:   "resources",
+			mutate: func(spec *webappv1.WebAppSpec) { spec.Resources = nil },
+			want:   []string{"WebAppPolicy payments: resources.requests.cpu is required"},
+		},
+		{
+			name:   "domain",
+			mutate: func(spec *webappv1.WebAppSpec) { spec.Ingress.Host = "app.notexample.com" },
+			want:   []string{"WebAppPolicy payments: host app.notexample.com is not in an allowed domain"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webapp := &webappv1.WebApp{Spec: *compliant.DeepCopy()}
+			tt.mutate(&webapp.Spec)
+			if got := PolicyViolations(webapp, []webappv1.WebAppPolicy{policy}); !slices.Equal(got, tt.want) {
+				t.Errorf("PolicyViolations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPolicyDefaults(t *testing.T) {
+	probe := &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz"}}}
+	policies := []webappv1.WebAppPolicy{
+		{ObjectMeta: metav1.ObjectMeta{Name: "a"}, Spec: webappv1.WebAppPolicySpec{Defaults: webappv1.WebAppPolicyDefaults{
+			Labels:         map[string]string{"team": "payments", "app": "other"},
+			ReadinessProbe: probe,
+		}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "b"}, Spec: webappv1.WebAppPolicySpec{Defaults: webappv1.WebAppPolicyDefaults{
+			Labels:      map[string]string{"team": "search"},
+			Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+		}}},
+	}
+	webapp := &webappv1.WebApp{ObjectMeta: metav1.ObjectMeta{Name: "web"}, Spec: webappv1.WebAppSpec{Image: "nginx"}}
+	deploy := BuildDeployment(webapp, DeploymentOptions{Policies: policies})
+
+	template := deploy.Spec.Template
+	if template.Labels["app"] != "web" || template.Labels["team"] != "payments" {
+		t.Errorf("labels = %v", template.Labels)
+	}
+	if len(deploy.Spec.Selector.MatchLabels) != 1 {
+		t.Errorf("selector changed: %v", deploy.Spec.Selector.MatchLabels)
+	}
+	if len(template.Spec.Tolerations) != 1 {
+		t.Errorf("tolerations = %v", template.Spec.Tolerations)
+	}
+	if template.Spec.Containers[0].ReadinessProbe == nil {
+		t.Errorf("readiness probe not applied")
+	}
+}
@@ -0,0 +1,61 @@
+package resources
+
+import (
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	StorageVolumePrefix = "storage-"
+)
+
+func PersistentVolumeClaimName(webapp *webappv1.WebApp, storage webappv1.StorageSpec) string {
+	return webapp.Name + "-" + storage.Name
+}
+
+func BuildPersistentVolumeClaim(webapp *webappv1.WebApp, storage webappv1.StorageSpec) *corev1.PersistentVolumeClaim {
+	accessModes := storage.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+	}
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PersistentVolumeClaimName(webapp, storage),
+			Namespace: webapp.Namespace,
+			Labels:    utils.GetCommonLabels(webapp),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      accessModes,
+			StorageClassName: storage.StorageClassName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: storage.Size,
+				},
+			},
+		},
+	}
+}
+
+// BuildStorageVolumes returns the pod volumes and webapp container mounts for spec.storage.
+func BuildStorageVolumes(webapp *webappv1.WebApp) ([]corev1.Volume, []corev1.VolumeMount) {
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	for _, storage := range webapp.Spec.Storage {
+		volumes = append(volumes, corev1.Volume{
+			Name: StorageVolumePrefix + storage.Name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: PersistentVolumeClaimName(webapp, storage),
+				},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      StorageVolumePrefix + storage.Name,
+			MountPath: storage.MountPath,
+		})
+	}
+	return volumes, mounts
+}
@@ -0,0 +1,69 @@
+package resources
+
+import (
+	"fmt"
+	"time"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/robfig/cron/v3"
+)
+
+// ApplySchedules applies the first schedule whose window contains now to the
+// WebApp spec (replicas, hibernate) and returns its name together with the
+// time left until the next window opens or closes. The name is empty when no
+// window is active, the duration is zero when there are no schedules.
+//
+// A window is active when its next end comes before its next start.
+func ApplySchedules(webapp *webappv1.WebApp, now time.Time) (string, time.Duration, error) {
+	var active *webappv1.ScheduleSpec
+	var next time.Time
+	for i := range webapp.Spec.Schedules {
+		schedule := &webapp.Spec.Schedules[i]
+		nextStart, nextEnd, err := nextTransitions(schedule, now)
+		if err != nil {
+			return "", 0, err
+		}
+		if active == nil && nextEnd.Before(nextStart) {
+			active = schedule
+		}
+		for _, t := range []time.Time{nextStart, nextEnd} {
+			if next.IsZero() || t.Before(next) {
+				next = t
+			}
+		}
+	}
+	if next.IsZero() {
+		return "", 0, nil
+	}
+	if active == nil {
+		return "", next.Sub(now), nil
+	}
+
+	if active.Replicas != nil {
+		webapp.Spec.Replicas = active.Replicas
+	}
+	if active.Hibernate {
+		webapp.Spec.Hibernate = true
+	}
+	return active.Name, next.Sub(now), nil
+}
+
+func nextTransitions(schedule *webappv1.ScheduleSpec, now time.Time) (time.Time, time.Time, error) {
+	location := time.UTC
+	if schedule.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(schedule.TimeZone); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("schedule %s: %w", schedule.Name, err)
+		}
+	}
+	start, err := cron.ParseStandard(schedule.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("schedule %s: start: %w", schedule.Name, err)
+	}
+	end, err := cron.ParseStandard(schedule.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("schedule %s: end: %w", schedule.Name, err)
+	}
+	local := now.In(location)
+	return start.Next(local), end.Next(local), nil
+}
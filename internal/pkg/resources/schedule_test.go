@@ -0,0 +1,79 @@
+package resources
+
+import (
+	"testing"
+	"time"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestApplySchedules(t *testing.T) {
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	schedules := []webappv1.ScheduleSpec{
+		{Name: "weekend", Start: "0 0 * * 6", End: "0 0 * * 1", TimeZone: "Asia/Seoul", Hibernate: true},
+		{Name: "night", Start: "0 20 * * *", End: "0 8 * * *", TimeZone: "Asia/Seoul", Replicas: ptr.To[int32](1)},
+	}
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		wantActive    string
+		wantReplicas  int32
+		wantHibernate bool
+		wantRequeue   time.Duration
+	}{
+		{
+			name:         "working hours",
+			now:          time.Date(2026, 10, 14, 10, 0, 0, 0, seoul), // Wednesday
+			wantReplicas: 3,
+			wantRequeue:  10 * time.Hour,
+		},
+		{
+			name:         "night",
+			now:          time.Date(2026, 10, 14, 23, 30, 0, 0, seoul),
+			wantActive:   "night",
+			wantReplicas: 1,
+			wantRequeue:  8*time.Hour + 30*time.Minute,
+		},
+		{
+			name:          "weekend wins over night",
+			now:           time.Date(2026, 10, 17, 21, 0, 0, 0, seoul), // Saturday
+			wantActive:    "weekend",
+			wantReplicas:  3,
+			wantHibernate: true,
+			wantRequeue:   11 * time.Hour,
+		},
+		{
+			name:         "evaluated in the schedule time zone",
+			now:          time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC), // 21:00 in Seoul
+			wantActive:   "night",
+			wantReplicas: 1,
+			wantRequeue:  11 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webapp := &webappv1.WebApp{Spec: webappv1.WebAppSpec{Replicas: ptr.To[int32](3), Schedules: schedules}}
+			active, requeue, err := ApplySchedules(webapp, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if active != tt.wantActive || *webapp.Spec.Replicas != tt.wantReplicas || webapp.Spec.Hibernate != tt.wantHibernate {
+				t.Errorf("ApplySchedules() active=%q replicas=%d hibernate=%v, want %q %d %v",
+					active, *webapp.Spec.Replicas, webapp.Spec.Hibernate, tt.wantActive, tt.wantReplicas, tt.wantHibernate)
+			}
+			if requeue != tt.wantRequeue {
+				t.Errorf("ApplySchedules() requeue = %v, want %v", requeue, tt.wantRequeue)
+			}
+		})
+	}
+
+	webapp := &webappv1.WebApp{Spec: webappv1.WebAppSpec{Schedules: []webappv1.ScheduleSpec{{Name: "bad", Start: "every day", End: "0 8 * * *"}}}}
+	if _, _, err := ApplySchedules(webapp, time.Now()); err == nil {
+		t.Error("ApplySchedules() accepted an invalid cron expression")
+	}
+}
@@ -0,0 +1,67 @@
+package resources
+
+import (
+	"maps"
+	"slices"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ZoneTopologyKey     = "topology.kubernetes.io/zone"
+	HostnameTopologyKey = "kubernetes.io/hostname"
+)
+
+// ApplyScheduling copies spec.scheduling into the pod spec, so that later
+// changes to the pod spec do not write into the (cached) WebApp.
+func ApplyScheduling(webapp *webappv1.WebApp, podSpec *corev1.PodSpec) {
+	scheduling := webapp.Spec.Scheduling
+	if scheduling == nil {
+		return
+	}
+
+	podSpec.NodeSelector = maps.Clone(scheduling.NodeSelector)
+	podSpec.Tolerations = slices.Clone(scheduling.Tolerations)
+	podSpec.Affinity = scheduling.Affinity.DeepCopy()
+	podSpec.PriorityClassName = scheduling.PriorityClassName
+	podSpec.TopologySpreadConstraints = BuildTopologySpreadConstraints(webapp)
+}
+
+func BuildTopologySpreadConstraints(webapp *webappv1.WebApp) []corev1.TopologySpreadConstraint {
+	scheduling := webapp.Spec.Scheduling
+	if scheduling == nil {
+		return nil
+	}
+
+	constraints := append([]corev1.TopologySpreadConstraint{}, scheduling.TopologySpreadConstraints...)
+	if !scheduling.SpreadAcrossZones {
+		return constraints
+	}
+
+	for _, topologyKey := range []string{ZoneTopologyKey, HostnameTopologyKey} {
+		if hasTopologyKey(constraints, topologyKey) {
+			continue
+		}
+		constraints = append(constraints, corev1.TopologySpreadConstraint{
+			MaxSkew:           1,
+			TopologyKey:       topologyKey,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: utils.GetCommonLabels(webapp),
+			},
+		})
+	}
+	return constraints
+}
+
+func hasTopologyKey(constraints []corev1.TopologySpreadConstraint, topologyKey string) bool {
+	for _, constraint := range constraints {
+		if constraint.TopologyKey == topologyKey {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package resources
+
+import (
+	"testing"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestApplySchedulingCopies(t *testing.T) {
+	webapp := &webappv1.WebApp{Spec: webappv1.WebAppSpec{Scheduling: &webappv1.SchedulingSpec{
+		NodeSelector: map[string]string{"pool": "web"},
+		Tolerations:  []corev1.Toleration{{Key: "dedicated", Value: "web"}},
+	}}}
+	podSpec := &corev1.PodSpec{}
+	ApplyScheduling(webapp, podSpec)
+
+	// e.g. a policy merge adding its own
+	podSpec.NodeSelector["zone"] = "a"
+	podSpec.Tolerations[0].Value = "other"
+	if len(webapp.Spec.Scheduling.NodeSelector) != 1 || webapp.Spec.Scheduling.Tolerations[0].Value != "web" {
+		t.Errorf("ApplyScheduling() shares the spec: %+v", webapp.Spec.Scheduling)
+	}
+}
@@ -0,0 +1,79 @@
+package resources
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	psaapi "k8s.io/pod-security-admission/api"
+	psapolicy "k8s.io/pod-security-admission/policy"
+	"k8s.io/utils/ptr"
+)
+
+// ApplyHardenedSecurityDefaults fills the unset security fields of the pod and
+// every container with a profile that passes the PSA "restricted" level:
+// runAsNonRoot, readOnlyRootFilesystem, no privilege escalation, drop ALL
+// capabilities and the RuntimeDefault seccomp profile.
+// Fields set on the WebApp are never overridden.
+func ApplyHardenedSecurityDefaults(podSpec *corev1.PodSpec) {
+	if podSpec.SecurityContext == nil {
+		podSpec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if podSpec.SecurityContext.RunAsNonRoot == nil {
+		podSpec.SecurityContext.RunAsNonRoot = ptr.To(true)
+	}
+	if podSpec.SecurityContext.SeccompProfile == nil {
+		podSpec.SecurityContext.SeccompProfile = &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		}
+	}
+
+	for i := range podSpec.InitContainers {
+		hardenContainer(&podSpec.InitContainers[i])
+	}
+	for i := range podSpec.Containers {
+		hardenContainer(&podSpec.Containers[i])
+	}
+}
+
+func hardenContainer(container *corev1.Container) {
+	if container.SecurityContext == nil {
+		container.SecurityContext = &corev1.SecurityContext{}
+	}
+	securityContext := container.SecurityContext
+	if securityContext.AllowPrivilegeEscalation == nil {
+		securityContext.AllowPrivilegeEscalation = ptr.To(false)
+	}
+	if securityContext.ReadOnlyRootFilesystem == nil {
+		securityContext.ReadOnlyRootFilesystem = ptr.To(true)
+	}
+	if securityContext.Capabilities == nil {
+		securityContext.Capabilities = &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		}
+	}
+}
+
+var podSecurityEvaluator psapolicy.Evaluator
+
+func init() {
+	evaluator, err := psapolicy.NewEvaluator(psapolicy.DefaultChecks())
+	if err != nil {
+		panic(err)
+	}
+	podSecurityEvaluator = evaluator
+}
+
+// EvaluatePodSecurity checks the pod template against the PSA enforce level of
+// the namespace. It returns the enforced level and, when the template would be
+// rejected, a description of the violations.
+func EvaluatePodSecurity(namespace *corev1.Namespace, template *corev1.PodTemplateSpec) (string, string) {
+	defaults := psaapi.Policy{
+		Enforce: psaapi.LevelVersion{Level: psaapi.LevelPrivileged, Version: psaapi.LatestVersion()},
+	}
+	// invalid labels fall back to restricted/latest, like the admission plugin
+	policy, _ := psaapi.PolicyToEvaluate(namespace.Labels, defaults)
+
+	result := psapolicy.AggregateCheckResults(podSecurityEvaluator.EvaluatePod(policy.Enforce, &template.ObjectMeta, &template.Spec))
+	if result.Allowed {
+		return string(policy.Enforce.Level), ""
+	}
+	return string(policy.Enforce.Level), result.ForbiddenDetail()
+}
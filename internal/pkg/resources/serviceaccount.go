@@ -0,0 +1,93 @@
+package resources
+
+import (
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceAccountName returns the ServiceAccount the pods run as, or "" for the
+// namespace default.
+func ServiceAccountName(webapp *webappv1.WebApp) string {
+	sa := webapp.Spec.ServiceAccount
+	if sa == nil {
+		return ""
+	}
+	if sa.Name == "" && sa.Create {
+		return webapp.Name
+	}
+	return sa.Name
+}
+
+// BuildServiceAccount returns nil unless spec.serviceAccount.create is set.
+func BuildServiceAccount(webapp *webappv1.WebApp) *corev1.ServiceAccount {
+	sa := webapp.Spec.ServiceAccount
+	if sa == nil || !sa.Create {
+		return nil
+	}
+
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ServiceAccountName(webapp),
+			Namespace:   webapp.Namespace,
+			Labels:      utils.GetCommonLabels(webapp),
+			Annotations: sa.Annotations,
+		},
+		AutomountServiceAccountToken: sa.AutomountServiceAccountToken,
+	}
+}
+
+// grantsRules reports whether spec.serviceAccount.rules are bound to a
+// dedicated ServiceAccount. Rules are never granted to the namespace default
+// ServiceAccount, which every other pod in the namespace runs as.
+func grantsRules(webapp *webappv1.WebApp) bool {
+	sa := webapp.Spec.ServiceAccount
+	name := ServiceAccountName(webapp)
+	return sa != nil && len(sa.Rules) > 0 && name != "" && name != "default"
+}
+
+// BuildRole returns nil when spec.serviceAccount.rules is empty or no
+// dedicated ServiceAccount is used.
+func BuildRole(webapp *webappv1.WebApp) *rbacv1.Role {
+	if !grantsRules(webapp) {
+		return nil
+	}
+
+	return &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      webapp.Name,
+			Namespace: webapp.Namespace,
+			Labels:    utils.GetCommonLabels(webapp),
+		},
+		Rules: webapp.Spec.ServiceAccount.Rules,
+	}
+}
+
+// BuildRoleBinding binds the Role from BuildRole to the pods' ServiceAccount.
+func BuildRoleBinding(webapp *webappv1.WebApp) *rbacv1.RoleBinding {
+	if !grantsRules(webapp) {
+		return nil
+	}
+
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      webapp.Name,
+			Namespace: webapp.Namespace,
+			Labels:    utils.GetCommonLabels(webapp),
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     webapp.Name,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      ServiceAccountName(webapp),
+				Namespace: webapp.Namespace,
+			},
+		},
+	}
+}
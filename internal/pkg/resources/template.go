@@ -0,0 +1,61 @@
+package resources
+
+import (
+	"maps"
+	"slices"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ApplyTemplate fills the fields the WebApp leaves unset from the template,
+// in the in-memory spec only. configData is merged by key and sidecars by
+// name, the WebApp winning in both.
+func ApplyTemplate(webapp *webappv1.WebApp, template *webappv1.WebAppTemplate) {
+	spec := &webapp.Spec
+	defaults := template.Spec
+
+	if len(defaults.ConfigData) > 0 {
+		configData := maps.Clone(defaults.ConfigData)
+		maps.Copy(configData, spec.ConfigData)
+		spec.ConfigData = configData
+	}
+	if spec.Ingress != nil && spec.Ingress.ClassName == "" {
+		spec.Ingress = spec.Ingress.DeepCopy()
+		spec.Ingress.ClassName = defaults.IngressClassName
+	}
+
+	var sidecars []corev1.Container
+	for _, sidecar := range defaults.Sidecars {
+		if i := slices.IndexFunc(spec.Sidecars, func(c corev1.Container) bool { return c.Name == sidecar.Name }); i >= 0 {
+			sidecars = append(sidecars, spec.Sidecars[i])
+		} else {
+			sidecars = append(sidecars, *sidecar.DeepCopy())
+		}
+	}
+	for _, sidecar := range spec.Sidecars {
+		if !slices.ContainsFunc(defaults.Sidecars, func(c corev1.Container) bool { return c.Name == sidecar.Name }) {
+			sidecars = append(sidecars, sidecar)
+		}
+	}
+	spec.Sidecars = sidecars
+
+	if spec.Scheduling == nil {
+		spec.Scheduling = defaults.Scheduling.DeepCopy()
+	}
+	if spec.Resources == nil {
+		spec.Resources = defaults.Resources.DeepCopy()
+	}
+	if spec.LivenessProbe == nil {
+		spec.LivenessProbe = defaults.LivenessProbe.DeepCopy()
+	}
+	if spec.ReadinessProbe == nil {
+		spec.ReadinessProbe = defaults.ReadinessProbe.DeepCopy()
+	}
+	if spec.SecurityContext == nil {
+		spec.SecurityContext = defaults.SecurityContext.DeepCopy()
+	}
+	if spec.PodSecurityContext == nil {
+		spec.PodSecurityContext = defaults.PodSecurityContext.DeepCopy()
+	}
+}
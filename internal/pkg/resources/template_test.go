@@ -0,0 +1,72 @@
+package resources
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyTemplate(t *testing.T) {
+	probe := &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz"}}}
+	template := &webappv1.WebAppTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "base"},
+		Spec: webappv1.WebAppTemplateSpec{
+			ConfigData:       map[string]string{"LOG_LEVEL": "info", "REGION": "eu"},
+			IngressClassName: "nginx",
+			Sidecars: []corev1.Container{
+				{Name: "proxy", Image: "envoy:1.30"},
+				{Name: "logs", Image: "fluent-bit:3"},
+			},
+			Resources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			},
+			LivenessProbe:  probe,
+			ReadinessProbe: probe,
+		},
+	}
+	webapp := &webappv1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "web"},
+		Spec: webappv1.WebAppSpec{
+			Image:          "nginx",
+			ConfigData:     map[string]string{"LOG_LEVEL": "debug"},
+			Ingress:        &webappv1.IngressSpec{Enabled: true},
+			Sidecars:       []corev1.Container{{Name: "proxy", Image: "envoy:1.31"}, {Name: "cache", Image: "redis"}},
+			ReadinessProbe: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/ready"}}},
+		},
+	}
+	ApplyTemplate(webapp, template)
+	spec := webapp.Spec
+
+	if want := map[string]string{"LOG_LEVEL": "debug", "REGION": "eu"}; !maps.Equal(spec.ConfigData, want) {
+		t.Errorf("configData = %v, want %v", spec.ConfigData, want)
+	}
+	if spec.Ingress.ClassName != "nginx" {
+		t.Errorf("ingress className = %q", spec.Ingress.ClassName)
+	}
+	var sidecars []string
+	for _, sidecar := range spec.Sidecars {
+		sidecars = append(sidecars, sidecar.Name+"="+sidecar.Image)
+	}
+	if want := []string{"proxy=envoy:1.31", "logs=fluent-bit:3", "cache=redis"}; !slices.Equal(sidecars, want) {
+		t.Errorf("sidecars = %v, want %v", sidecars, want)
+	}
+	if spec.Resources == nil || spec.LivenessProbe == nil {
+		t.Errorf("resources and liveness probe not applied")
+	}
+	if spec.ReadinessProbe.HTTPGet.Path != "/ready" {
+		t.Errorf("readiness probe overridden: %v", spec.ReadinessProbe.HTTPGet.Path)
+	}
+	if template.Spec.ConfigData["LOG_LEVEL"] != "info" {
+		t.Errorf("template modified: %v", template.Spec.ConfigData)
+	}
+
+	deploy := BuildDeployment(webapp, DeploymentOptions{})
+	if container := deploy.Spec.Template.Spec.Containers[0]; container.LivenessProbe == nil || container.Resources.Requests.Cpu().IsZero() {
+		t.Errorf("webapp container = %+v", container)
+	}
+}
@@ -0,0 +1,232 @@
+// Package sharding spreads WebApps across operator replicas. Every replica
+// renews a Lease of its own; the replicas with a live Lease split the
+// hash space of WebApp namespace/name keys into equal ranges.
+package sharding
+
+import (
+	"context"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"time"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// ShardLabel records the replica that reconciles the WebApp.
+	ShardLabel = "webapp.crdlego.com/shard"
+	// MemberLabel marks the Leases of the shard members.
+	MemberLabel = "webapp.crdlego.com/shard-member"
+
+	leaseNamePrefix      = "webapp-shard-"
+	DefaultLeaseDuration = 15 * time.Second
+)
+
+// Coordinator keeps the Lease of this replica alive, tracks the other
+// members and re-enqueues the WebApps this replica gains when members join
+// or leave. It runs on every replica, so leader election must be disabled.
+type Coordinator struct {
+	// Client writes the Lease and lists WebApps from the cache.
+	Client client.Client
+	// APIReader reads Leases without requiring a cache for them.
+	APIReader client.Reader
+	// Namespace holds the Leases.
+	Namespace string
+	// Identity is unique per replica, usually the pod name.
+	Identity string
+	// LeaseDuration defaults to DefaultLeaseDuration. Leases are renewed
+	// every third of it.
+	LeaseDuration time.Duration
+	// Clock defaults to the real clock.
+	Clock clock.WithTicker
+
+	mu      sync.RWMutex
+	members []string
+	events  chan event.GenericEvent
+}
+
+// NewCoordinator returns a Coordinator for the replica identity.
+func NewCoordinator(c client.Client, reader client.Reader, namespace, identity string) *Coordinator {
+	return &Coordinator{
+		Client:    c,
+		APIReader: reader,
+		Namespace: namespace,
+		Identity:  identity,
+		events:    make(chan event.GenericEvent),
+	}
+}
+
+// Source enqueues the WebApps handed over to this replica by a rebalance.
+func (c *Coordinator) Source() source.Source {
+	return source.Channel(c.events, &handler.EnqueueRequestForObject{})
+}
+
+// Owns reports whether this replica reconciles the WebApp. Nothing is owned
+// until the first membership sync, whose rebalance enqueues the WebApps
+// skipped meanwhile.
+func (c *Coordinator) Owns(key types.NamespacedName) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return ShardFor(key, c.members) == c.Identity
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (c *Coordinator) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable.
+func (c *Coordinator) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithValues("shard", c.Identity)
+	ticker := c.clock().NewTicker(c.leaseDuration() / 3)
+	defer ticker.Stop()
+	for {
+		if err := c.sync(ctx); err != nil {
+			log.Error(err, "failed to sync shard membership")
+		}
+		select {
+		case <-ctx.Done():
+			// release the range right away instead of waiting for the Lease to expire
+			lease := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Namespace: c.Namespace, Name: c.leaseName()}}
+			if err := c.Client.Delete(context.Background(), lease); err != nil && !errors.IsNotFound(err) {
+				log.Error(err, "failed to release shard lease")
+			}
+			return nil
+		case <-ticker.C():
+		}
+	}
+}
+
+// sync renews the Lease of this replica and rebalances when the set of live
+// members changed.
+func (c *Coordinator) sync(ctx context.Context) error {
+	if err := c.renew(ctx); err != nil {
+		return err
+	}
+
+	var leases coordinationv1.LeaseList
+	if err := c.APIReader.List(ctx, &leases, client.InNamespace(c.Namespace), client.HasLabels{MemberLabel}); err != nil {
+		return err
+	}
+	members := LiveMembers(leases.Items, c.clock().Now())
+	if !slices.Contains(members, c.Identity) {
+		members = append(members, c.Identity)
+		slices.Sort(members)
+	}
+
+	c.mu.Lock()
+	previous := c.members
+	c.members = members
+	c.mu.Unlock()
+	if slices.Equal(previous, members) {
+		return nil
+	}
+	logf.FromContext(ctx).Info("Shard members changed", "members", members)
+	return c.rebalance(ctx, previous)
+}
+
+func (c *Coordinator) renew(ctx context.Context) error {
+	now := metav1.NewMicroTime(c.clock().Now())
+	lease := &coordinationv1.Lease{}
+	err := c.APIReader.Get(ctx, types.NamespacedName{Namespace: c.Namespace, Name: c.leaseName()}, lease)
+	if errors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: c.Namespace,
+				Name:      c.leaseName(),
+				Labels:    map[string]string{MemberLabel: "true"},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(c.Identity),
+				LeaseDurationSeconds: ptr.To(int32(c.leaseDuration().Seconds())),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		return c.Client.Create(ctx, lease)
+	} else if err != nil {
+		return err
+	}
+	lease.Spec.RenewTime = &now
+	return c.Client.Update(ctx, lease)
+}
+
+// rebalance enqueues the WebApps this replica owns under the new members but
+// did not own under the previous ones.
+func (c *Coordinator) rebalance(ctx context.Context, previous []string) error {
+	var webapps webappv1.WebAppList
+	if err := c.Client.List(ctx, &webapps); err != nil {
+		return err
+	}
+	for i := range webapps.Items {
+		webapp := &webapps.Items[i]
+		key := client.ObjectKeyFromObject(webapp)
+		if !c.Owns(key) || ShardFor(key, previous) == c.Identity {
+			continue
+		}
+		select {
+		case c.events <- event.GenericEvent{Object: webapp}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+func (c *Coordinator) leaseName() string {
+	return leaseNamePrefix + c.Identity
+}
+
+func (c *Coordinator) leaseDuration() time.Duration {
+	if c.LeaseDuration == 0 {
+		return DefaultLeaseDuration
+	}
+	return c.LeaseDuration
+}
+
+func (c *Coordinator) clock() clock.WithTicker {
+	if c.Clock == nil {
+		return clock.RealClock{}
+	}
+	return c.Clock
+}
+
+// LiveMembers returns the sorted holders of the Leases that have not expired.
+func LiveMembers(leases []coordinationv1.Lease, now time.Time) []string {
+	var members []string
+	for _, lease := range leases {
+		spec := lease.Spec
+		if spec.HolderIdentity == nil || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expiry := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+		if now.Before(expiry) {
+			members = append(members, *spec.HolderIdentity)
+		}
+	}
+	slices.Sort(members)
+	return slices.Compact(members)
+}
+
+// ShardFor maps the key onto one of the sorted members, each owning an
+// equal range of the 32-bit FNV-1a hash space. It returns "" without members.
+func ShardFor(key types.NamespacedName, members []string) string {
+	if len(members) == 0 {
+		return ""
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key.String()))
+	return members[uint64(h.Sum32())*uint64(len(members))>>32]
+}
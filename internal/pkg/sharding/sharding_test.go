@@ -0,0 +1,186 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestLiveMembers(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	lease := func(holder string, renewed time.Duration) coordinationv1.Lease {
+		return coordinationv1.Lease{Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To(holder),
+			LeaseDurationSeconds: ptr.To[int32](15),
+			RenewTime:            ptr.To(metav1.NewMicroTime(now.Add(-renewed))),
+		}}
+	}
+	leases := []coordinationv1.Lease{
+		lease("operator-c", 5*time.Second),
+		lease("operator-a", 14*time.Second),
+		lease("operator-b", 16*time.Second),
+		{},
+	}
+	if got, want := LiveMembers(leases, now), []string{"operator-a", "operator-c"}; !slices.Equal(got, want) {
+		t.Errorf("LiveMembers() = %v, want %v", got, want)
+	}
+}
+
+func TestShardFor(t *testing.T) {
+	if got := ShardFor(types.NamespacedName{Namespace: "default", Name: "web"}, nil); got != "" {
+		t.Errorf("ShardFor() without members = %q", got)
+	}
+
+	// every member gets a fair part of the keys
+	members := []string{"operator-a", "operator-b", "operator-c"}
+	counts := map[string]int{}
+	for i := range 3000 {
+		counts[ShardFor(types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("web-%d", i)}, members)]++
+	}
+	for _, member := range members {
+		if counts[member] < 800 || counts[member] > 1200 {
+			t.Errorf("member %s owns %d of 3000 keys", member, counts[member])
+		}
+	}
+
+	// a single member owns everything
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	if got := ShardFor(key, []string{"operator-a"}); got != "operator-a" {
+		t.Errorf("ShardFor() single member = %q", got)
+	}
+}
+
+const testNamespace = "webapp-system"
+
+// newCoordinator returns the Coordinator of operator-a on a fake client
+// holding the objects, with a buffered event channel so that a rebalance
+// does not block the test.
+func newCoordinator(t *testing.T, now time.Time, objs ...client.Object) (*Coordinator, *testingclock.FakeClock) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := webappv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	coordinator := NewCoordinator(c, c, testNamespace, "operator-a")
+	coordinator.events = make(chan event.GenericEvent, len(objs))
+	clock := testingclock.NewFakeClock(now)
+	coordinator.Clock = clock
+	return coordinator, clock
+}
+
+// drain returns the keys enqueued so far, sorted.
+func drain(c *Coordinator) []string {
+	var keys []string
+	for {
+		select {
+		case e := <-c.events:
+			keys = append(keys, client.ObjectKeyFromObject(e.Object).String())
+		default:
+			slices.Sort(keys)
+			return keys
+		}
+	}
+}
+
+func TestCoordinatorRebalance(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	objs := []client.Object{&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: leaseNamePrefix + "operator-b", Labels: map[string]string{MemberLabel: "true"}},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To("operator-b"),
+			LeaseDurationSeconds: ptr.To[int32](15),
+			RenewTime:            ptr.To(metav1.NewMicroTime(now)),
+		},
+	}}
+	var keys []types.NamespacedName
+	for i := range 20 {
+		key := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("web-%d", i)}
+		keys = append(keys, key)
+		objs = append(objs, &webappv1.WebApp{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}})
+	}
+	coordinator, clock := newCoordinator(t, now, objs...)
+
+	// wantKeys returns the keys owned by member under members, sorted
+	wantKeys := func(member string, members ...string) []string {
+		var want []string
+		for _, key := range keys {
+			if ShardFor(key, members) == member {
+				want = append(want, key.String())
+			}
+		}
+		slices.Sort(want)
+		return want
+	}
+
+	// the first sync enqueues everything this replica owns
+	if err := coordinator.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := drain(coordinator), wantKeys("operator-a", "operator-a", "operator-b"); !slices.Equal(got, want) {
+		t.Errorf("first sync enqueued %v, want %v", got, want)
+	}
+
+	// nothing is enqueued while the members stay the same
+	clock.Step(5 * time.Second)
+	if err := coordinator.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := drain(coordinator); len(got) > 0 {
+		t.Errorf("unchanged members enqueued %v", got)
+	}
+
+	// operator-b expires, only its WebApps are handed over
+	clock.Step(15 * time.Second)
+	if err := coordinator.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := drain(coordinator), wantKeys("operator-b", "operator-a", "operator-b"); !slices.Equal(got, want) {
+		t.Errorf("rebalance enqueued %v, want %v", got, want)
+	}
+}
+
+func TestCoordinatorReleasesLease(t *testing.T) {
+	coordinator, _ := newCoordinator(t, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	key := types.NamespacedName{Namespace: testNamespace, Name: coordinator.leaseName()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- coordinator.Start(ctx) }()
+	for {
+		err := coordinator.Client.Get(context.Background(), key, &coordinationv1.Lease{})
+		if err == nil {
+			break
+		} else if !errors.IsNotFound(err) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+	if err := coordinator.Client.Get(context.Background(), key, &coordinationv1.Lease{}); !errors.IsNotFound(err) {
+		t.Errorf("lease after stop: %v, want NotFound", err)
+	}
+}
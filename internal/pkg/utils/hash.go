@@ -13,3 +13,9 @@ func HashMapString(data map[string]string) string {
 	sum := sha1.Sum(raw)
 	return hex.EncodeToString(sum[:])
 }
+
+func HashObject(obj any) string {
+	raw, _ := json.Marshal(obj)
+	sum := sha1.Sum(raw)
+	return hex.EncodeToString(sum[:])
+}
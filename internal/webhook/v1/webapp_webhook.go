@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/registry"
+	"github.com/hoon77/crd-operator/internal/pkg/resources"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var webapplog = logf.Log.WithName("webapp-resource")
+
+// SetupWebAppWebhookWithManager registers the webhooks for WebApp in the manager.
+// WebApp v1 implements conversion.Convertible against the v2 hub, so this
+// serves the /convert endpoint used by the CRD conversion webhook, next to
+// the validating webhook enforcing the WebAppPolicies and the allowed
+// registries.
+func SetupWebAppWebhookWithManager(mgr ctrl.Manager, allowedRegistries []string) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&webappv1.WebApp{}).
+		WithValidator(&WebAppCustomValidator{Client: mgr.GetClient(), AllowedRegistries: allowedRegistries}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-webapp-crdlego-com-v1-webapp,mutating=false,failurePolicy=fail,sideEffects=None,groups=webapp.crdlego.com,resources=webapps,verbs=create;update,versions=v1,name=vwebapp-v1.kb.io,admissionReviewVersions=v1
+
+// WebAppCustomValidator rejects WebApps violating the WebAppPolicies of
+// their namespace or using images outside the allowed registries. v2
+// requests are converted to v1 before they reach it.
+type WebAppCustomValidator struct {
+	Client client.Reader
+	// AllowedRegistries are registries or repository prefixes. Empty allows
+	// every registry.
+	AllowedRegistries []string
+}
+
+var _ webhook.CustomValidator = &WebAppCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator.
+func (v *WebAppCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	webapp, ok := obj.(*webappv1.WebApp)
+	if !ok {
+		return nil, fmt.Errorf("expected a WebApp object but got %T", obj)
+	}
+	webapplog.Info("Validation for WebApp upon creation", "name", webapp.GetName())
+	return nil, v.validate(ctx, webapp)
+}
+
+// ValidateUpdate implements webhook.CustomValidator. Only spec changes are
+// checked, so that the operator can still manage the finalizer and labels of
+// a WebApp that violates a policy created after it.
+func (v *WebAppCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldWebapp, ok := oldObj.(*webappv1.WebApp)
+	if !ok {
+		return nil, fmt.Errorf("expected a WebApp object for the oldObj but got %T", oldObj)
+	}
+	webapp, ok := newObj.(*webappv1.WebApp)
+	if !ok {
+		return nil, fmt.Errorf("expected a WebApp object for the newObj but got %T", newObj)
+	}
+	if !webapp.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldWebapp.Spec, webapp.Spec) {
+		return nil, nil
+	}
+	webapplog.Info("Validation for WebApp upon update", "name", webapp.GetName())
+	return nil, v.validate(ctx, webapp)
+}
+
+// ValidateDelete implements webhook.CustomValidator.
+func (v *WebAppCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks the WebApp merged with its WebAppTemplate, if it exists.
+func (v *WebAppCustomValidator) validate(ctx context.Context, webapp *webappv1.WebApp) error {
+	if ref := webapp.Spec.TemplateRef; ref != nil {
+		template := &webappv1.WebAppTemplate{}
+		err := v.Client.Get(ctx, types.NamespacedName{Namespace: webapp.Namespace, Name: ref.Name}, template)
+		if err == nil {
+			webapp = webapp.DeepCopy()
+			resources.ApplyTemplate(webapp, template)
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	if len(v.AllowedRegistries) > 0 {
+		if disallowed := registry.DisallowedImages(resources.WebAppImages(webapp), v.AllowedRegistries); len(disallowed) > 0 {
+			return fmt.Errorf("images are not from an allowed registry: %s", strings.Join(disallowed, ", "))
+		}
+	}
+	policies, err := resources.ListPolicies(ctx, v.Client, webapp.Namespace)
+	if err != nil {
+		return err
+	}
+	if violations := resources.PolicyViolations(webapp, policies); len(violations) > 0 {
+		return fmt.Errorf("%s", strings.Join(violations, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	webappv1 "github.com/hoon77/crd-operator/api/v1"
+	"github.com/hoon77/crd-operator/internal/pkg/resources"
+)
+
+// newValidator returns a validator reading the payments namespace, a policy
+// selecting it and the given objects from a fake client.
+func newValidator(t *testing.T, allowedRegistries []string, objs ...client.Object) *WebAppCustomValidator {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := webappv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	objs = append(objs,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}},
+		&webappv1.WebAppPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+			Spec: webappv1.WebAppPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+				Limits: webappv1.WebAppPolicyLimits{
+					MaxReplicas:       ptr.To[int32](3),
+					RequiredResources: []corev1.ResourceName{corev1.ResourceCPU},
+				},
+			},
+		},
+	)
+	return &WebAppCustomValidator{
+		Client:            fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		AllowedRegistries: allowedRegistries,
+	}
+}
+
+func newWebApp(mutate func(*webappv1.WebAppSpec)) *webappv1.WebApp {
+	webapp := &webappv1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "payments"},
+		Spec: webappv1.WebAppSpec{
+			Image:     "ghcr.io/example/web:1.0",
+			Replicas:  ptr.To[int32](2),
+			Resources: &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}},
+		},
+	}
+	mutate(&webapp.Spec)
+	return webapp
+}
+
+func TestValidateCreate(t *testing.T) {
+	template := &webappv1.WebAppTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "payments"},
+		Spec: webappv1.WebAppTemplateSpec{
+			Resources: &corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")}},
+			Sidecars:  []corev1.Container{{Name: "proxy", Image: "envoyproxy/envoy:v1.31"}},
+		},
+	}
+
+	tests := []struct {
+		name              string
+		allowedRegistries []string
+		mutate            func(*webappv1.WebAppSpec)
+		// wantErr is a substring of the expected error, empty when allowed
+		wantErr string
+	}{
+		{name: "compliant", mutate: func(*webappv1.WebAppSpec) {}},
+		{
+			name:    "policy violation",
+			mutate:  func(spec *webappv1.WebAppSpec) { spec.Replicas = ptr.To[int32](5) },
+			wantErr: "WebAppPolicy payments: replicas 5 exceed the maximum of 3",
+		},
+		{
+			name:              "disallowed registry",
+			allowedRegistries: []string{"ghcr.io/example"},
+			mutate:            func(spec *webappv1.WebAppSpec) { spec.Image = "ghcr.io.evil.com/example/web:1.0" },
+			wantErr:           "images are not from an allowed registry: ghcr.io.evil.com/example/web:1.0",
+		},
+		{
+			name: "template fills the required resources",
+			mutate: func(spec *webappv1.WebAppSpec) {
+				spec.Resources = nil
+				spec.TemplateRef = &webappv1.WebAppTemplateReference{Name: "base"}
+			},
+		},
+		{
+			name:              "template sidecar from a disallowed registry",
+			allowedRegistries: []string{"ghcr.io/example"},
+			mutate: func(spec *webappv1.WebAppSpec) {
+				spec.TemplateRef = &webappv1.WebAppTemplateReference{Name: "base"}
+			},
+			wantErr: "envoyproxy/envoy:v1.31",
+		},
+		{
+			name: "missing template is validated without it",
+			mutate: func(spec *webappv1.WebAppSpec) {
+				spec.Resources = nil
+				spec.TemplateRef = &webappv1.WebAppTemplateReference{Name: "missing"}
+			},
+			wantErr: "resources.requests.cpu is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := newValidator(t, tt.allowedRegistries, template.DeepCopy())
+			webapp := newWebApp(tt.mutate)
+			_, err := validator.ValidateCreate(context.Background(), webapp)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("ValidateCreate() = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("ValidateCreate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+			if len(webapp.Spec.Sidecars) > 0 {
+				t.Errorf("ValidateCreate() merged the template into the request object")
+			}
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	validator := newValidator(t, []string{"ghcr.io/example"})
+	// violates the policy created after it
+	old := newWebApp(func(spec *webappv1.WebAppSpec) { spec.Replicas = ptr.To[int32](5) })
+
+	tests := []struct {
+		name    string
+		mutate  func(*webappv1.WebApp)
+		wantErr bool
+	}{
+		{
+			name:   "finalizer",
+			mutate: func(webapp *webappv1.WebApp) { webapp.Finalizers = []string{resources.WebAppFinalizer} },
+		},
+		{
+			name:   "labels",
+			mutate: func(webapp *webappv1.WebApp) { webapp.Labels = map[string]string{"team": "payments"} },
+		},
+		{
+			name:    "spec",
+			mutate:  func(webapp *webappv1.WebApp) { webapp.Spec.Image = "docker.io/library/nginx:1.27" },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webapp := old.DeepCopy()
+			tt.mutate(webapp)
+			_, err := validator.ValidateUpdate(context.Background(), old, webapp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateUpdate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}